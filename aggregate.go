@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// aggregator 在 -aggregate 模式下按目标缓存结果，每满 N 条输出一行批次汇总
+type aggregator struct {
+	size             int
+	onlyErrors       bool // 只输出包含失败的批次
+	timeoutAsFailure bool // 批次丢包率是否包含超时，与整体统计一致
+	pending          map[string][]PingResult
+	printed          map[string]int // 每个目标已输出的结果数，用于标注序号区间
+}
+
+func newAggregator(size int, timeoutAsFailure bool) *aggregator {
+	return &aggregator{
+		size:             size,
+		timeoutAsFailure: timeoutAsFailure,
+		pending:          make(map[string][]PingResult),
+		printed:          make(map[string]int),
+	}
+}
+
+// add 加入一条结果，批次满时立即输出
+func (a *aggregator) add(result PingResult) {
+	batch := append(a.pending[result.label()], result)
+	if len(batch) < a.size {
+		a.pending[result.label()] = batch
+		return
+	}
+	a.print(result.label(), batch)
+	a.pending[result.label()] = nil
+}
+
+// flush 输出所有未满的批次，按 targets 的顺序
+func (a *aggregator) flush(targets []string) {
+	for _, t := range targets {
+		if batch := a.pending[t]; len(batch) > 0 {
+			a.print(t, batch)
+			a.pending[t] = nil
+		}
+	}
+}
+
+func (a *aggregator) print(target string, batch []PingResult) {
+	start := a.printed[target] + 1
+	a.printed[target] += len(batch)
+
+	stats := newTargetStats(0, a.timeoutAsFailure)
+	for _, r := range batch {
+		stats.add(r)
+	}
+	sum := stats.summary()
+	if a.onlyErrors && sum.Failed == 0 {
+		return
+	}
+
+	color := ColorGreen
+	switch {
+	case sum.Success == 0:
+		color = ColorRed
+	case sum.Failed > 0:
+		color = ColorYellow
+	}
+
+	fmt.Printf("[%d-%d] %s%s: %d 次, 丢包 %.1f%%", start, a.printed[target], color, target, sum.Sent, sum.LossPercent)
+	if sum.Success > 0 {
+		fmt.Printf(", 最小/平均/最大 = %v/%v/%v",
+			formatDuration(sum.Min, time.Millisecond), formatDuration(sum.Avg, time.Millisecond), formatDuration(sum.Max, time.Millisecond))
+	}
+	fmt.Println(ColorReset)
+}
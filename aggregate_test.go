@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAggregatorSuccesses 是 -aggregate 批次中包含成功结果时的回归测试 (批次统计曾缺少分位数估计器而崩溃)
+func TestAggregatorSuccesses(t *testing.T) {
+	a := newAggregator(2, true)
+	for i := range 5 {
+		a.add(PingResult{Target: "example.com:80", Success: true, ResponseTime: time.Duration(i+1) * time.Millisecond})
+	}
+	if a.printed["example.com:80"] != 4 {
+		t.Errorf("已输出 %d 条结果, 期望 4 (两个完整批次)", a.printed["example.com:80"])
+	}
+	a.flush([]string{"example.com:80"})
+	if a.printed["example.com:80"] != 5 || len(a.pending["example.com:80"]) != 0 {
+		t.Errorf("flush 后已输出 %d 条, 剩余 %d 条", a.printed["example.com:80"], len(a.pending["example.com:80"]))
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// alertSink 实现 -bell 和 -notify: 探测失败时响铃 (写到 stderr，不干扰 stdout 的结构化输出)
+// 或发送桌面通知。同一检查在 interval 内只提醒一次，持续故障时不会每轮都打扰。
+type alertSink struct {
+	bell     bool
+	notify   bool
+	interval time.Duration
+	last     map[string]time.Time // 检查名称 -> 上一次提醒的时间
+}
+
+func newAlertSink(bell, notify bool, interval time.Duration) *alertSink {
+	return &alertSink{bell: bell, notify: notify, interval: interval, last: make(map[string]time.Time)}
+}
+
+func (s *alertSink) WriteHeader([]string, string) error { return nil }
+
+func (s *alertSink) WriteResult(result PingResult, seq int) error {
+	if result.Success {
+		return nil
+	}
+	now := time.Now()
+	if last, ok := s.last[result.label()]; ok && now.Sub(last) < s.interval {
+		return nil
+	}
+	s.last[result.label()] = now
+
+	if s.bell {
+		fmt.Fprint(os.Stderr, "\a")
+	}
+	if s.notify {
+		cmd := notifyCommand("ping-tool: "+result.label()+" 失败", fmt.Sprintf("[%d] %s%v", seq, errorTag(result.Error), result.Error))
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("发送桌面通知: %w", err)
+		}
+		// 通知程序可能需要一点时间才退出，不阻塞探测循环
+		go cmd.Wait()
+	}
+	return nil
+}
+
+func (s *alertSink) EndRound([]PingResult, int) error            { return nil }
+func (s *alertSink) WriteSummary([]string, []*targetStats) error { return nil }
+func (s *alertSink) Close() error                                { return nil }
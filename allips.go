@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ipCheck 是 -all-ips 展开后的一个检查: 目标不变 (Host 头和 SNI 仍使用主机名)，
+// 连接固定到主机名解析出的其中一个地址
+type ipCheck struct {
+	target string
+	name   string // 原检查名称加上地址，如 example.com [192.0.2.1]
+	group  string // 原检查名称，没有展开时为空
+	ip     string
+	opts   *Options // 带有该地址 -resolve 覆盖的选项副本
+}
+
+// targetHost 从目标中取出主机名和端口，支持 URL、host:port 和不带端口的写法
+func targetHost(target string, opts *Options) (host, port string) {
+	if strings.Contains(target, "://") {
+		if u, err := url.Parse(target); err == nil {
+			return u.Hostname(), u.Port()
+		}
+	}
+	host, port, err := net.SplitHostPort(withDefaultPort(target, opts.defaultPort()))
+	if err != nil {
+		return target, ""
+	}
+	return host, port
+}
+
+// expandAllIPs 把每个主机名目标展开为每个解析地址一个检查。IP 目标和已被
+// -resolve/-hostfile 覆盖的主机名只有一个地址，保持原样
+func expandAllIPs(targets, names []string, opts *Options) ([]ipCheck, error) {
+	var checks []ipCheck
+	for i, t := range targets {
+		label := checkLabel(t, names[i])
+		host, port := targetHost(t, opts)
+		if _, ok := opts.overrideAddr(host, port); ok || net.ParseIP(host) != nil {
+			checks = append(checks, ipCheck{target: t, name: names[i], opts: opts})
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		addrs, err := opts.lookupIPAddr(ctx, host)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %w", host, err)
+		}
+		seen := make(map[string]bool)
+		for _, addr := range addrs {
+			ip := addr.String()
+			if seen[ip] {
+				continue
+			}
+			seen[ip] = true
+			o := *opts
+			o.Resolve = resolveFlag{}
+			maps.Copy(o.Resolve, opts.Resolve)
+			o.Resolve[strings.ToLower(host)] = ip
+			checks = append(checks, ipCheck{
+				target: t,
+				name:   fmt.Sprintf("%s [%s]", label, ip),
+				group:  label,
+				ip:     ip,
+				opts:   &o,
+			})
+		}
+	}
+	return checks, nil
+}
+
+// ipFamily 返回地址的协议族 ipv4 或 ipv6，用于 JSON 输出和按协议族汇总
+func ipFamily(ip string) string {
+	if addr := net.ParseIP(ip); addr != nil && addr.To4() == nil {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// familyTotal 是同一主机名下一个协议族所有地址的合计
+type familyTotal struct {
+	sent, success int
+	lost          float64 // 按 LossPercent 折算的丢失次数，与各地址的丢包率口径一致
+	total, worst  time.Duration
+}
+
+// printIPGroups 在统计信息之后按主机名分组输出每个地址的丢包和延迟，便于找出有问题的节点。
+// 主机名同时解析出 IPv4 和 IPv6 地址时追加按协议族的合计，区分是哪个协议族的连通性问题
+func printIPGroups(checks []ipCheck, stats []*targetStats) {
+	for i := 0; i < len(checks); {
+		group := checks[i].group
+		if group == "" {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(checks) && checks[j].group == group {
+			j++
+		}
+
+		fmt.Printf("%s=== 各地址对比: %s ===%s\n", ColorCyan, group, ColorReset)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "地址\t发送\t丢包\t平均\t最大\t")
+		families := make(map[string]*familyTotal)
+		for k := i; k < j; k++ {
+			sum := stats[k].summary()
+			fmt.Fprintf(w, "%s\t%d\t%.1f%%\t%s\t%s\t\n", checks[k].ip, sum.Sent, sum.LossPercent, formatGroupAvg(sum.Avg, sum.Success), formatGroupAvg(sum.Max, sum.Success))
+
+			f := families[ipFamily(checks[k].ip)]
+			if f == nil {
+				f = &familyTotal{}
+				families[ipFamily(checks[k].ip)] = f
+			}
+			f.sent += sum.Sent
+			f.success += sum.Success
+			f.lost += sum.LossPercent / 100 * float64(sum.Sent)
+			f.total += sum.Avg * time.Duration(sum.Success)
+			f.worst = max(f.worst, sum.Max)
+		}
+		if len(families) > 1 {
+			for _, family := range []struct{ key, label string }{{"ipv4", "IPv4 合计"}, {"ipv6", "IPv6 合计"}} {
+				f := families[family.key]
+				loss, avg := 0.0, time.Duration(0)
+				if f.sent > 0 {
+					loss = f.lost / float64(f.sent) * 100
+				}
+				if f.success > 0 {
+					avg = f.total / time.Duration(f.success)
+				}
+				fmt.Fprintf(w, "%s\t%d\t%.1f%%\t%s\t%s\t\n", family.label, f.sent, loss, formatGroupAvg(avg, f.success), formatGroupAvg(f.worst, f.success))
+			}
+		}
+		w.Flush()
+		fmt.Println()
+		i = j
+	}
+}
+
+// formatGroupAvg 格式化对比表中的延迟，没有成功样本时显示 -
+func formatGroupAvg(d time.Duration, success int) string {
+	if success == 0 {
+		return "-"
+	}
+	return formatDuration(d, time.Millisecond)
+}
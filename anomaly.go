@@ -0,0 +1,35 @@
+package main
+
+import "time"
+
+// anomalyQuantile 是 -anomaly-factor 对比的分位数
+const anomalyQuantile = 0.95
+
+// anomalyDetector 实现 -anomaly-factor: 用 P² 流式估计本次运行中已有成功样本的 p95，
+// 预热之后响应时间超过 p95 × factor 的成功样本标记为延迟异常，不需要固定的阈值
+type anomalyDetector struct {
+	factor float64
+	warmup int // 开始判断前需要的成功样本数
+	p95    *p2Quantile
+	seen   int
+}
+
+func newAnomalyDetector(factor float64, warmup int) *anomalyDetector {
+	return &anomalyDetector{factor: factor, warmup: warmup, p95: newP2Quantile(anomalyQuantile)}
+}
+
+// check 用此前的分布判断 result 是否异常并写入结果，然后把样本计入分布
+func (d *anomalyDetector) check(result *PingResult) {
+	if !result.Success {
+		return
+	}
+	if d.seen >= d.warmup {
+		threshold := time.Duration(float64(d.p95.value()) * d.factor)
+		if result.ResponseTime > threshold {
+			result.Anomaly = true
+			result.AnomalyThreshold = threshold
+		}
+	}
+	d.p95.add(result.ResponseTime)
+	d.seen++
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// baselineSchemaVersion 是基线文件格式的版本号，独立于 -o json 的输出版本，
+// 只有基线文件本身的字段发生不兼容的变化时才递增，否则已保存的基线会全部失效
+const baselineSchemaVersion = 1
+
+// baselineFile 是 -save-baseline 写出、-baseline 读取的延迟基线
+type baselineFile struct {
+	SchemaVersion int                      `json:"schema_version"`
+	GeneratedAt   time.Time                `json:"generated_at"`
+	Targets       map[string]baselineEntry `json:"targets"`
+}
+
+// baselineEntry 是单个目标的基线数据
+type baselineEntry struct {
+	AvgMs       float64   `json:"avg_ms"`
+	Sent        int       `json:"sent"`
+	LossPercent float64   `json:"loss_percent"`
+	SamplesMs   []float64 `json:"samples_ms,omitempty"` // 保留的成功响应时间，供 -compare-to 做显著性检验
+}
+
+func loadBaseline(path string) (*baselineFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var base baselineFile
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, err
+	}
+	if base.SchemaVersion != baselineSchemaVersion {
+		return nil, fmt.Errorf("不支持的基线版本 %d", base.SchemaVersion)
+	}
+	return &base, nil
+}
+
+// baselineSink 在运行结束时把各目标的平均延迟保存为新的基线
+type baselineSink struct {
+	path string
+}
+
+func (s *baselineSink) WriteHeader([]string, string) error { return nil }
+func (s *baselineSink) WriteResult(PingResult, int) error  { return nil }
+func (s *baselineSink) EndRound([]PingResult, int) error   { return nil }
+func (s *baselineSink) Close() error                       { return nil }
+
+func (s *baselineSink) WriteSummary(targets []string, stats []*targetStats) error {
+	doc := baselineFile{
+		SchemaVersion: baselineSchemaVersion,
+		GeneratedAt:   time.Now(),
+		Targets:       make(map[string]baselineEntry, len(targets)),
+	}
+	for i, t := range targets {
+		sum := stats[i].summary()
+		if sum.Success == 0 {
+			continue // 没有成功样本的目标不能作为基线
+		}
+		doc.Targets[checkLabel(t, stats[i].name)] = baselineEntry{
+			AvgMs:       durationMs(sum.Avg),
+			Sent:        sum.Sent,
+			LossPercent: sum.LossPercent,
+			SamplesMs:   successSamplesMs(stats[i]),
+		}
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, append(data, '\n'))
+}
+
+// successSamplesMs 返回缓冲区中保留的成功响应时间 (毫秒)，数量受 -max-samples 限制
+func successSamplesMs(stats *targetStats) []float64 {
+	var ms []float64
+	for _, r := range stats.samples(0) {
+		if r.Success {
+			ms = append(ms, durationMs(r.ResponseTime))
+		}
+	}
+	return ms
+}
+
+// compareBaseline 输出本次平均延迟相对基线的变化，返回超过 threshold 百分比的退化目标数。
+// confidence 大于 0 时 (-compare-to) 还对本次和基线的响应时间样本做 Mann-Whitney U 检验，
+// 只有差异在该置信水平下显著时才算退化，避免把噪声误判为退化
+func compareBaseline(base *baselineFile, targets []string, stats []*targetStats, threshold, confidence float64) int {
+	title := fmt.Sprintf("退化阈值 %.1f%%", threshold)
+	if confidence > 0 {
+		title += fmt.Sprintf("，置信水平 %g%%", confidence*100)
+	}
+	fmt.Printf("%s=== 基线对比 (%s) ===%s\n", ColorCyan, title, ColorReset)
+	regressions := 0
+	for i, t := range targets {
+		entry, ok := base.Targets[t]
+		if !ok {
+			fmt.Printf("%s: 基线中没有该目标\n", t)
+			continue
+		}
+		sum := stats[i].summary()
+		if sum.Success == 0 {
+			fmt.Printf("%s%s: 本次没有成功的响应 (基线平均 %.1fms)%s\n", ColorRed, t, entry.AvgMs, ColorReset)
+			regressions++
+			continue
+		}
+
+		avg := durationMs(sum.Avg)
+		delta := 0.0
+		if entry.AvgMs > 0 {
+			delta = (avg - entry.AvgMs) / entry.AvgMs * 100
+		}
+		regressed := delta > threshold
+		significance := ""
+		if confidence > 0 {
+			current := successSamplesMs(stats[i])
+			if len(current) < mannWhitneyMinSamples || len(entry.SamplesMs) < mannWhitneyMinSamples {
+				// 样本不足时无法判断，退回只比较平均值
+				significance = fmt.Sprintf("，样本不足 %d 个无法检验显著性", mannWhitneyMinSamples)
+			} else {
+				p := mannWhitneyU(current, entry.SamplesMs)
+				if p < 1-confidence {
+					significance = fmt.Sprintf("，p=%.3g 显著", p)
+				} else {
+					significance = fmt.Sprintf("，p=%.3g 不显著", p)
+					regressed = false
+				}
+			}
+		}
+		color, verdict := ColorGreen, "正常"
+		if regressed {
+			color, verdict = ColorRed, "退化"
+			regressions++
+		}
+		fmt.Printf("%s%s: 平均 %.1fms，基线 %.1fms，%+.1f%%%s %s%s\n",
+			color, t, avg, entry.AvgMs, delta, significance, verdict, ColorReset)
+	}
+	fmt.Println()
+	return regressions
+}
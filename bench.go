@@ -0,0 +1,110 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// benchReport 汇总 -bench 模式下所有请求的结果
+type benchReport struct {
+	total     int
+	success   int
+	elapsed   time.Duration
+	latencies []time.Duration // 成功请求的响应时间
+	failures  map[string]int  // 失败原因 -> 次数
+}
+
+// runBench 以 concurrency 个 worker 共发起 total 次探测，多个目标时轮流分配。
+// 与监控循环不同，请求之间没有间隔，用于快速评估服务承载能力。
+// stagger 大于 0 时每个 worker 在该窗口内随机等待后才发出首个请求。
+func runBench(targets []string, opts *Options, total, concurrency int, stagger time.Duration) *benchReport {
+	stop := stopOnSignal()
+	results := make(chan PingResult, concurrency)
+	var next atomic.Int64
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if stagger > 0 && !sleepOrStop(rand.N(stagger), stop) {
+				return
+			}
+			for !stopped(stop) {
+				n := int(next.Add(1)) - 1
+				if n >= total {
+					return
+				}
+				results <- ping(targets[n%len(targets)], opts)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	report := &benchReport{failures: make(map[string]int)}
+	for r := range results {
+		report.total++
+		if r.Success {
+			report.success++
+			report.latencies = append(report.latencies, r.ResponseTime)
+			continue
+		}
+		reason := classifyError(r.Error)
+		if r.StatusCode > 0 {
+			reason = fmt.Sprintf("HTTP %d", r.StatusCode)
+		}
+		report.failures[reason]++
+	}
+	report.elapsed = time.Since(start)
+	slices.Sort(report.latencies)
+	return report
+}
+
+// percentile 按最近秩法计算已排序样本的百分位数
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
+
+func (r *benchReport) print() {
+	fmt.Printf("\n%s=== 压测结果 ===%s\n", ColorCyan, ColorReset)
+	fmt.Printf("完成请求: %d, 耗时: %v\n", r.total, formatDuration(r.elapsed, time.Millisecond))
+	if r.elapsed > 0 {
+		fmt.Printf("吞吐量: %.1f 请求/秒\n", float64(r.total)/r.elapsed.Seconds())
+	}
+	fmt.Printf("成功: %d, 失败: %d\n", r.success, r.total-r.success)
+
+	if len(r.latencies) > 0 {
+		fmt.Println("\n延迟分布 (成功请求):")
+		fmt.Printf("  最小  %v\n", formatDuration(r.latencies[0], time.Microsecond))
+		for _, p := range []float64{50, 90, 95, 99} {
+			fmt.Printf("  p%-4g %v\n", p, formatDuration(percentile(r.latencies, p), time.Microsecond))
+		}
+		fmt.Printf("  最大  %v\n", formatDuration(r.latencies[len(r.latencies)-1], time.Microsecond))
+	}
+
+	if len(r.failures) > 0 {
+		fmt.Println("\n错误分类:")
+		reasons := slices.SortedFunc(maps.Keys(r.failures), func(a, b string) int {
+			return cmp.Or(cmp.Compare(r.failures[b], r.failures[a]), strings.Compare(a, b))
+		})
+		for _, reason := range reasons {
+			fmt.Printf("  %-10s %d\n", reason, r.failures[reason])
+		}
+	}
+	fmt.Println()
+}
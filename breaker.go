@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// circuitBreaker 实现 -circuit-failures: 目标连续失败达到阈值后熔断，
+// 之后只按 -circuit-interval 的慢速间隔复查，复查成功即恢复正常轮询。
+type circuitBreaker struct {
+	threshold int
+	recheck   time.Duration
+	targets   []circuitState
+}
+
+// circuitState 是单个目标的熔断状态
+type circuitState struct {
+	failures  int // 连续失败次数
+	open      bool
+	nextCheck time.Time
+}
+
+func newCircuitBreaker(n, threshold int, recheck time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, recheck: recheck, targets: make([]circuitState, n)}
+}
+
+// allow 判断本轮是否探测第 i 个目标，熔断中的目标只在复查时间到达后探测
+func (b *circuitBreaker) allow(i int, now time.Time) bool {
+	st := &b.targets[i]
+	return !st.open || !now.Before(st.nextCheck)
+}
+
+// record 根据探测结果更新熔断状态，状态变化时在标准错误输出提示
+func (b *circuitBreaker) record(i int, target string, success bool, now time.Time) {
+	st := &b.targets[i]
+	if success {
+		if st.open {
+			fmt.Fprintf(os.Stderr, ColorGreen+"熔断恢复: %s 复查成功，恢复正常轮询\n"+ColorReset, target)
+		}
+		*st = circuitState{}
+		return
+	}
+
+	st.failures++
+	if st.open {
+		st.nextCheck = now.Add(b.recheck)
+		return
+	}
+	if st.failures >= b.threshold {
+		st.open = true
+		st.nextCheck = now.Add(b.recheck)
+		fmt.Fprintf(os.Stderr, ColorYellow+"熔断: %s 连续失败 %d 次，改为每 %v 复查一次\n"+ColorReset,
+			target, st.failures, b.recheck)
+	}
+}
+
+// printOpen 在统计末尾列出仍处于熔断状态的目标
+func (b *circuitBreaker) printOpen(targets []string) {
+	var open []string
+	for i, st := range b.targets {
+		if st.open {
+			open = append(open, fmt.Sprintf("%s (连续失败 %d 次)", targets[i], st.failures))
+		}
+	}
+	if len(open) > 0 {
+		fmt.Printf("%s熔断中的目标: %s%s\n\n", ColorYellow, strings.Join(open, ", "), ColorReset)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// countingConn 统计经过连接的字节数。HTTP 传输层会在后台 goroutine 中读写连接，所以使用原子计数。
+type countingConn struct {
+	net.Conn
+	sent     atomic.Int64
+	received atomic.Int64
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.received.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.sent.Add(int64(n))
+	return n, err
+}
+
+// unwrapCountingConn 从 HTTP 传输层拿到的连接中取出 countingConn，TLS 连接需要先取底层连接
+func unwrapCountingConn(conn net.Conn) *countingConn {
+	if tc, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		conn = tc.NetConn()
+	}
+	c, _ := conn.(*countingConn)
+	return c
+}
+
+// parseBytes 解析字节数，支持 B/KB/MB/GB 后缀 (1024 进制，不区分大小写，可省略 B)，如 512、64KB、10M
+func parseBytes(s string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	num, mult := strings.TrimSuffix(upper, "B"), int64(1)
+	for i, suffix := range []string{"K", "M", "G"} {
+		if strings.HasSuffix(num, suffix) {
+			num, mult = strings.TrimSuffix(num, suffix), int64(1)<<(10*(i+1))
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(num), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("无效的字节数 %q", s)
+	}
+	return n * mult, nil
+}
+
+// downloadWarnRatio 是 -max-download 发出提醒的比例
+const downloadWarnRatio = 0.8
+
+// downloadCap 实现 -max-download: 累计所有探测接收的字节数，达到上限时停止运行，
+// 超过上限的 80% 时在标准错误提醒一次
+type downloadCap struct {
+	limit  int64
+	total  int64
+	warned bool
+}
+
+// add 计入一次探测接收的字节数，返回是否已达到上限
+func (c *downloadCap) add(n int64) bool {
+	c.total += n
+	if !c.warned && c.total < c.limit && float64(c.total) >= float64(c.limit)*downloadWarnRatio {
+		c.warned = true
+		fmt.Fprintf(os.Stderr, ColorYellow+"注意: 已接收 %s，接近 -max-download 上限 %s\n"+ColorReset, formatBytes(c.total), formatBytes(c.limit))
+	}
+	return c.total >= c.limit
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// redisCommand 按 RESP 协议把命令编码为多条批量字符串组成的数组
+func redisCommand(args ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	return b.String()
+}
+
+// readCacheLine 读取一行以 \r\n 结尾的回复
+func readCacheLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", explainReset(err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// redisAuth 返回 AUTH 命令的参数: -redis-password 优先，否则与 redis-cli 一样读取
+// REDISCLI_AUTH 环境变量；指定 -redis-user 时使用 Redis 6 的 ACL 形式 AUTH user password
+func (o *Options) redisAuth() []string {
+	password := o.RedisPassword
+	if password == "" {
+		password = os.Getenv("REDISCLI_AUTH")
+	}
+	if password == "" {
+		return nil
+	}
+	if o.RedisUser != "" {
+		return []string{"AUTH", o.RedisUser, password}
+	}
+	return []string{"AUTH", password}
+}
+
+// redisSession: [AUTH -> +OK] -> PING -> +PONG
+func redisSession(conn net.Conn, r *bufio.Reader, opts *Options) (string, error) {
+	if auth := opts.redisAuth(); auth != nil {
+		if _, err := conn.Write([]byte(redisCommand(auth...))); err != nil {
+			return "", explainReset(err)
+		}
+		line, err := readCacheLine(r)
+		if err != nil {
+			return "", err
+		}
+		if line != "+OK" {
+			return "", fmt.Errorf("AUTH 失败: %s", strings.TrimPrefix(line, "-"))
+		}
+	}
+	if _, err := conn.Write([]byte(redisCommand("PING"))); err != nil {
+		return "", explainReset(err)
+	}
+	line, err := readCacheLine(r)
+	if err != nil {
+		return "", err
+	}
+	if line != "+PONG" {
+		// 常见的是 -NOAUTH (需要密码) 和 -LOADING (正在加载数据)
+		return "", fmt.Errorf("PING 回复不是 +PONG: %s", line)
+	}
+	return "", nil
+}
+
+// memcachedSession: version -> VERSION x.y.z，返回版本号作为 -v 显示的服务器信息
+func memcachedSession(conn net.Conn, r *bufio.Reader, _ *Options) (string, error) {
+	if _, err := conn.Write([]byte("version\r\n")); err != nil {
+		return "", explainReset(err)
+	}
+	line, err := readCacheLine(r)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(line, "VERSION ") {
+		return "", fmt.Errorf("version 回复不是 VERSION: %s", line)
+	}
+	return line, nil
+}
+
+// cacheSessions 是各缓存 ping 类型的命令实现
+var cacheSessions = map[string]func(net.Conn, *bufio.Reader, *Options) (string, error){
+	"redis":     redisSession,
+	"memcached": memcachedSession,
+}
+
+// pingCache 在 TCP 连接上发送缓存服务的 PING 命令 (memcached 为 version) 并校验回复，
+// 响应时间包含连接和命令往返
+func pingCache(target string, opts *Options) PingResult {
+	result := PingResult{Target: target}
+	session := cacheSessions[strings.ToLower(opts.PingType)]
+
+	target = withDefaultPort(target, opts.defaultPort())
+	start := time.Now()
+	raw, err := opts.dial("tcp", target)
+	if err != nil {
+		result.ResponseTime = time.Since(start)
+		result.Error = err
+		return result
+	}
+	conn := &countingConn{Conn: raw}
+	defer conn.Close()
+	result.RemoteAddr = conn.RemoteAddr().String()
+	if err := conn.SetDeadline(start.Add(opts.Timeout)); err != nil {
+		result.Error = err
+		return result
+	}
+
+	result.Banner, err = session(conn, bufio.NewReader(conn), opts)
+	result.ResponseTime = time.Since(start)
+	result.BytesSent, result.BytesRecv = conn.sent.Load(), conn.received.Load()
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.Success = true
+	return result
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// certInfo 是 -dump-chain 输出的一张证书
+type certInfo struct {
+	Subject   string
+	Issuer    string
+	NotBefore time.Time
+	NotAfter  time.Time
+	SANs      []string // DNS 名称和 IP 地址
+}
+
+// chainReport 是服务器发送的完整证书链以及发现的问题
+type chainReport struct {
+	Certs  []certInfo
+	Issues []string
+}
+
+// presentedChain 返回服务器发送的证书: 握手成功时取自连接状态，
+// 证书校验失败时取自错误 (Go 在校验失败时保留了未通过校验的证书)
+func presentedChain(state *tls.ConnectionState, err error) []*x509.Certificate {
+	if state != nil {
+		return state.PeerCertificates
+	}
+	var verifyErr *tls.CertificateVerificationError
+	if errors.As(err, &verifyErr) {
+		return verifyErr.UnverifiedCertificates
+	}
+	return nil
+}
+
+// inspectChain 实现 -dump-chain: 列出每张证书，并检查过期或未生效的证书、
+// 顺序错误 (某张证书不是由下一张签发) 和无法构建到受信任根证书的链 (通常是缺少中间证书)
+func inspectChain(certs []*x509.Certificate, roots *x509.CertPool, now time.Time) *chainReport {
+	if len(certs) == 0 {
+		return nil
+	}
+	report := &chainReport{}
+	for i, cert := range certs {
+		info := certInfo{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+			SANs:      append([]string(nil), cert.DNSNames...),
+		}
+		for _, ip := range cert.IPAddresses {
+			info.SANs = append(info.SANs, ip.String())
+		}
+		report.Certs = append(report.Certs, info)
+
+		switch {
+		case now.After(cert.NotAfter):
+			report.Issues = append(report.Issues, fmt.Sprintf("第 %d 张证书 (%s) 已于 %s 过期", i, info.Subject, cert.NotAfter.Format("2006-01-02")))
+		case now.Before(cert.NotBefore):
+			report.Issues = append(report.Issues, fmt.Sprintf("第 %d 张证书 (%s) 在 %s 之前尚未生效", i, info.Subject, cert.NotBefore.Format("2006-01-02")))
+		}
+		if i+1 < len(certs) && cert.CheckSignatureFrom(certs[i+1]) != nil {
+			report.Issues = append(report.Issues, fmt.Sprintf("第 %d 张证书不是由第 %d 张签发 (顺序错误或混入了无关的证书)", i, i+1))
+		}
+	}
+
+	// 按叶子证书的有效期校验，过期问题已单独报告，这里只关心能否构建出链
+	opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool(), CurrentTime: certs[0].NotBefore.Add(time.Second)}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	var unknown x509.UnknownAuthorityError
+	if _, err := certs[0].Verify(opts); errors.As(err, &unknown) {
+		last := certs[len(certs)-1]
+		if bytes.Equal(last.RawIssuer, last.RawSubject) {
+			report.Issues = append(report.Issues, fmt.Sprintf("根证书 %s 不受信任", last.Subject))
+		} else {
+			report.Issues = append(report.Issues, fmt.Sprintf("缺少中间证书: 找不到签发 %s 的证书 (服务器没有发送完整的证书链，或根证书不受信任)", last.Issuer))
+		}
+	}
+	return report
+}
+
+// printChain 在 -v 输出中显示证书链
+func printChain(report *chainReport) {
+	fmt.Println("    证书链:")
+	for i, c := range report.Certs {
+		fmt.Printf("      [%d] %s\n", i, c.Subject)
+		fmt.Printf("          签发者: %s\n", c.Issuer)
+		fmt.Printf("          有效期: %s ~ %s\n", c.NotBefore.Format("2006-01-02"), c.NotAfter.Format("2006-01-02"))
+		if len(c.SANs) > 0 {
+			fmt.Printf("          SAN: %s\n", strings.Join(c.SANs, ", "))
+		}
+	}
+	for _, issue := range report.Issues {
+		fmt.Printf("    %s证书链问题: %s%s\n", ColorYellow, issue, ColorReset)
+	}
+}
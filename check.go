@@ -0,0 +1,544 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxCheckBody 是响应体断言 (-check 引用 body、-expect-json) 最多读取的字节数
+const maxCheckBody = 1 << 20
+
+// checkEnv 是 -check 表达式和 -check-script 脚本求值时可访问的变量
+type checkEnv struct {
+	result   PingResult // 探测结果，ok 为求值前的内置判定
+	pingType string
+	body     string
+	header   http.Header
+}
+
+// checkExpr 是编译后的 -check 表达式。
+//
+// 语法示例: status==200 && latency<200ms && body contains "ok"
+//
+// 支持的变量: status (数字), latency (时长), body (字符串), header("名称") (字符串)，
+// 以及适用于所有 ping 类型的 ok (内置判定，布尔值), type, target, error, category (字符串),
+// retries, bytes_sent, bytes_received (数字), server_time (时长), corrupted (布尔值)。
+// 支持的运算符: == != < <= > >= contains && || ! 以及括号。
+// 字面量: 数字 (200)、时长 (200ms, 1.5s)、双引号字符串 ("ok")、true/false。
+// # 开始到行尾为注释，便于在 -check-script 文件中分行书写。
+type checkExpr struct {
+	source   string
+	root     checkNode
+	needBody bool
+}
+
+// compileCheck 解析表达式，并用零值环境试算一次以尽早发现类型错误
+func compileCheck(source string) (*checkExpr, error) {
+	p := &checkParser{src: source}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("位置 %d 处存在多余内容 %q", tok.pos, tok.text)
+	}
+
+	expr := &checkExpr{source: source, root: root, needBody: p.usesBody}
+	if _, err := expr.Eval(checkEnv{header: http.Header{}}); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// loadCheckScript 从文件读取 -check-script 脚本，语法与 -check 相同
+func loadCheckScript(path string) (*checkExpr, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := compileCheck(string(data))
+	if err != nil {
+		return nil, err
+	}
+	expr.source = path
+	return expr, nil
+}
+
+// applyScript 用 -check-script 的结果覆盖内置判定: 脚本可以把失败改判为成功，反之亦然
+func applyScript(script *checkExpr, env checkEnv, result *PingResult) {
+	ok, err := script.Eval(env)
+	switch {
+	case err != nil:
+		result.Success = false
+		result.Error = fmt.Errorf("检查脚本求值失败: %v", err)
+	case !ok:
+		result.Success = false
+		if result.Error == nil {
+			result.Error = fmt.Errorf("检查脚本判定失败: %s", script.source)
+		}
+	default:
+		result.Success = true
+		result.Error = nil
+	}
+}
+
+// Eval 对表达式求值，结果必须为布尔值
+func (e *checkExpr) Eval(env checkEnv) (bool, error) {
+	v, err := e.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	if v.kind != valBool {
+		return false, fmt.Errorf("表达式结果不是布尔值")
+	}
+	return v.b, nil
+}
+
+type valueKind int
+
+const (
+	valNumber valueKind = iota
+	valDuration
+	valString
+	valBool
+)
+
+func (k valueKind) String() string {
+	switch k {
+	case valNumber:
+		return "数字"
+	case valDuration:
+		return "时长"
+	case valString:
+		return "字符串"
+	default:
+		return "布尔值"
+	}
+}
+
+type checkValue struct {
+	kind valueKind
+	num  float64
+	dur  time.Duration
+	str  string
+	b    bool
+}
+
+type checkNode interface {
+	eval(env checkEnv) (checkValue, error)
+}
+
+type literalNode struct{ v checkValue }
+
+func (n literalNode) eval(checkEnv) (checkValue, error) { return n.v, nil }
+
+type varNode struct{ name string }
+
+func (n varNode) eval(env checkEnv) (checkValue, error) {
+	r := env.result
+	switch n.name {
+	case "status":
+		return checkValue{kind: valNumber, num: float64(r.StatusCode)}, nil
+	case "latency":
+		return checkValue{kind: valDuration, dur: r.ResponseTime}, nil
+	case "body":
+		return checkValue{kind: valString, str: env.body}, nil
+	case "ok":
+		return checkValue{kind: valBool, b: r.Success}, nil
+	case "type":
+		return checkValue{kind: valString, str: strings.ToLower(env.pingType)}, nil
+	case "target":
+		return checkValue{kind: valString, str: r.Target}, nil
+	case "error":
+		errText := ""
+		if r.Error != nil {
+			errText = r.Error.Error()
+		}
+		return checkValue{kind: valString, str: errText}, nil
+	case "category":
+		return checkValue{kind: valString, str: classifyError(r.Error)}, nil
+	case "retries":
+		return checkValue{kind: valNumber, num: float64(r.Retries)}, nil
+	case "bytes_sent":
+		return checkValue{kind: valNumber, num: float64(r.BytesSent)}, nil
+	case "bytes_received":
+		return checkValue{kind: valNumber, num: float64(r.BytesRecv)}, nil
+	case "server_time":
+		return checkValue{kind: valDuration, dur: r.ServerTime}, nil
+	case "corrupted":
+		return checkValue{kind: valBool, b: r.Corrupted}, nil
+	}
+	return checkValue{}, fmt.Errorf("未知变量 %q", n.name)
+}
+
+// checkVars 是除 body 和 header() 以外可以在表达式中引用的变量
+var checkVars = map[string]bool{
+	"status": true, "latency": true, "ok": true, "type": true, "target": true,
+	"error": true, "category": true, "retries": true, "bytes_sent": true,
+	"bytes_received": true, "server_time": true, "corrupted": true,
+}
+
+type headerNode struct{ name string }
+
+func (n headerNode) eval(env checkEnv) (checkValue, error) {
+	return checkValue{kind: valString, str: env.header.Get(n.name)}, nil
+}
+
+type notNode struct{ operand checkNode }
+
+func (n notNode) eval(env checkEnv) (checkValue, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return v, err
+	}
+	if v.kind != valBool {
+		return v, fmt.Errorf("! 只能作用于布尔值，得到%s", v.kind)
+	}
+	return checkValue{kind: valBool, b: !v.b}, nil
+}
+
+type logicNode struct {
+	op          string
+	left, right checkNode
+}
+
+func (n logicNode) eval(env checkEnv) (checkValue, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return l, err
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return r, err
+	}
+	if l.kind != valBool || r.kind != valBool {
+		return checkValue{}, fmt.Errorf("%s 两侧必须为布尔值", n.op)
+	}
+	if n.op == "&&" {
+		return checkValue{kind: valBool, b: l.b && r.b}, nil
+	}
+	return checkValue{kind: valBool, b: l.b || r.b}, nil
+}
+
+type compareNode struct {
+	op          string
+	left, right checkNode
+}
+
+func (n compareNode) eval(env checkEnv) (checkValue, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return l, err
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return r, err
+	}
+
+	if n.op == "contains" {
+		if l.kind != valString || r.kind != valString {
+			return checkValue{}, fmt.Errorf("contains 两侧必须为字符串")
+		}
+		return checkValue{kind: valBool, b: strings.Contains(l.str, r.str)}, nil
+	}
+	if l.kind != r.kind {
+		return checkValue{}, fmt.Errorf("无法比较%s和%s", l.kind, r.kind)
+	}
+
+	var cmp int
+	switch l.kind {
+	case valNumber:
+		cmp = compareOrdered(l.num, r.num)
+	case valDuration:
+		cmp = compareOrdered(l.dur, r.dur)
+	case valString:
+		cmp = strings.Compare(l.str, r.str)
+	case valBool:
+		if n.op != "==" && n.op != "!=" {
+			return checkValue{}, fmt.Errorf("布尔值不支持 %s", n.op)
+		}
+		if l.b != r.b {
+			cmp = 1
+		}
+	}
+
+	var b bool
+	switch n.op {
+	case "==":
+		b = cmp == 0
+	case "!=":
+		b = cmp != 0
+	case "<":
+		b = cmp < 0
+	case "<=":
+		b = cmp <= 0
+	case ">":
+		b = cmp > 0
+	case ">=":
+		b = cmp >= 0
+	}
+	return checkValue{kind: valBool, b: b}, nil
+}
+
+func compareOrdered[T float64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokDuration
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type checkToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type checkParser struct {
+	src      string
+	tokens   []checkToken
+	next     int
+	usesBody bool
+}
+
+func (p *checkParser) tokenize() error {
+	src := p.src
+	for i := 0; i < len(src); {
+		// 按 UTF-8 解码，多字节字符 (如全角空格、中文) 不会被拆成多个错误的字符
+		c, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(c):
+			i += size
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '(':
+			p.tokens = append(p.tokens, checkToken{tokLParen, "(", i})
+			i++
+		case c == ')':
+			p.tokens = append(p.tokens, checkToken{tokRParen, ")", i})
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return fmt.Errorf("位置 %d 处的字符串未闭合", i)
+			}
+			s, err := strconv.Unquote(src[i : j+1])
+			if err != nil {
+				return fmt.Errorf("位置 %d 处的字符串无效: %v", i, err)
+			}
+			p.tokens = append(p.tokens, checkToken{tokString, s, i})
+			i = j + 1
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			kind := tokNumber
+			for j < len(src) {
+				if r, size := utf8.DecodeRuneInString(src[j:]); unicode.IsLetter(r) {
+					kind = tokDuration
+					j += size
+					continue
+				}
+				break
+			}
+			p.tokens = append(p.tokens, checkToken{kind, src[i:j], i})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(src) {
+				r, n := utf8.DecodeRuneInString(src[j:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+					break
+				}
+				j += n
+			}
+			word := src[i:j]
+			kind := tokIdent
+			if word == "contains" {
+				kind = tokOp
+			}
+			p.tokens = append(p.tokens, checkToken{kind, word, i})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return fmt.Errorf("位置 %d 处存在无法识别的字符 %q", i, c)
+			}
+			p.tokens = append(p.tokens, checkToken{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	p.tokens = append(p.tokens, checkToken{tokEOF, "", len(src)})
+	return nil
+}
+
+func (p *checkParser) peek() checkToken { return p.tokens[p.next] }
+
+func (p *checkParser) advance() checkToken {
+	tok := p.tokens[p.next]
+	if tok.kind != tokEOF {
+		p.next++
+	}
+	return tok
+}
+
+// parseOr 解析 a || b，优先级最低
+func (p *checkParser) parseOr() (checkNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "||" {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *checkParser) parseAnd() (checkNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "&&" {
+		p.advance()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *checkParser) parseUnary() (checkNode, error) {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == "!" {
+		p.advance()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *checkParser) parseCompare() (checkNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	tok := p.peek()
+	if tok.kind != tokOp {
+		return left, nil
+	}
+	switch tok.text {
+	case "==", "!=", "<", "<=", ">", ">=", "contains":
+		p.advance()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return compareNode{op: tok.text, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *checkParser) parsePrimary() (checkNode, error) {
+	tok := p.advance()
+	switch tok.kind {
+	case tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.advance().kind != tokRParen {
+			return nil, fmt.Errorf("位置 %d 处的括号未闭合", tok.pos)
+		}
+		return node, nil
+	case tokNumber:
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("位置 %d 处的数字无效: %s", tok.pos, tok.text)
+		}
+		return literalNode{checkValue{kind: valNumber, num: n}}, nil
+	case tokDuration:
+		d, err := time.ParseDuration(tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("位置 %d 处的时长无效: %s", tok.pos, tok.text)
+		}
+		return literalNode{checkValue{kind: valDuration, dur: d}}, nil
+	case tokString:
+		return literalNode{checkValue{kind: valString, str: tok.text}}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			return literalNode{checkValue{kind: valBool, b: tok.text == "true"}}, nil
+		case "body":
+			p.usesBody = true
+			return varNode{name: tok.text}, nil
+		case "header":
+			if p.advance().kind != tokLParen {
+				return nil, fmt.Errorf("位置 %d 处 header 后应为 (", tok.pos)
+			}
+			name := p.advance()
+			if name.kind != tokString {
+				return nil, fmt.Errorf("位置 %d 处 header() 的参数必须为字符串", name.pos)
+			}
+			if p.advance().kind != tokRParen {
+				return nil, fmt.Errorf("位置 %d 处 header() 缺少 )", name.pos)
+			}
+			return headerNode{name: name.text}, nil
+		}
+		if checkVars[tok.text] {
+			return varNode{name: tok.text}, nil
+		}
+		return nil, fmt.Errorf("位置 %d 处存在未知变量 %q", tok.pos, tok.text)
+	case tokEOF:
+		return nil, fmt.Errorf("表达式意外结束")
+	}
+	return nil, fmt.Errorf("位置 %d 处存在意外的 %q", tok.pos, tok.text)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckEval(t *testing.T) {
+	env := checkEnv{
+		result: PingResult{
+			Target:       "https://example.com/",
+			Success:      true,
+			StatusCode:   200,
+			ResponseTime: 150 * time.Millisecond,
+			ServerTime:   20 * time.Millisecond,
+			Retries:      1,
+		},
+		pingType: "HTTPS",
+		body:     `{"status":"正常","version":"1.2"}`,
+		header:   http.Header{"Content-Type": {"application/json"}, "X-Region": {"华东"}},
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// 优先级: && 高于 ||，括号改变结合顺序
+		{"status == 200", true},
+		{"status == 500 || status == 200 && latency < 200ms", true},
+		{"(status == 500 || status == 200) && latency > 200ms", false},
+		{"status == 200 || status == 500 && latency > 200ms", true},
+		{"(status == 200 || status == 500) && latency > 200ms", false},
+		{"!(status == 200) || ok", true},
+		{"!ok", false},
+		{"!!ok && true", true},
+		// 时长与字面量比较
+		{"latency < 200ms", true},
+		{"latency <= 150ms", true},
+		{"latency > 0.1s", true},
+		{"latency >= 1s", false},
+		{"server_time < latency", true},
+		// 字符串、header() 和 contains
+		{`header("content-type") == "application/json"`, true},
+		{`header("Content-Type") contains "json"`, true},
+		{`header("X-Missing") == ""`, true},
+		{`body contains "version"`, true},
+		{`body contains "missing"`, false},
+		{`type == "https" && target contains "example.com"`, true},
+		// 非 ASCII 字符串和全角空格
+		{`body contains "正常"`, true},
+		{`header("X-Region") == "华东"　&& status == 200`, true},
+		{`body contains "异常"`, false},
+		// 注释
+		{"status == 200 # 只检查状态码\n&& retries == 1", true},
+	}
+	for _, tt := range tests {
+		expr, err := compileCheck(tt.expr)
+		if err != nil {
+			t.Errorf("compileCheck(%q) 失败: %v", tt.expr, err)
+			continue
+		}
+		got, err := expr.Eval(env)
+		if err != nil {
+			t.Errorf("%q 求值失败: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q = %v, 期望 %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"rtt < 200ms", `未知变量 "rtt"`},
+		{"状态 == 200", `未知变量 "状态"`},
+		{"status == 200ms", "无法比较"},
+		{`latency < "fast"`, "无法比较"},
+		{"status", "不是布尔值"},
+		{"status && ok", "两侧必须为布尔值"},
+		{"!status", "只能作用于布尔值"},
+		{`status contains "2"`, "contains 两侧必须为字符串"},
+		{"ok < true", "布尔值不支持"},
+		{"(status == 200", "括号未闭合"},
+		{"status == 200)", "多余内容"},
+		{"status ==", "意外结束"},
+		{`body contains "ok`, "字符串未闭合"},
+		{"header(1)", "参数必须为字符串"},
+		{"header", "header 后应为 ("},
+		{"status == 200 & ok", "无法识别的字符"},
+		{"latency < 200xs", "时长无效"},
+		{"status == 2.0.0", "数字无效"},
+	}
+	for _, tt := range tests {
+		_, err := compileCheck(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("compileCheck(%q) 错误 = %v, 期望包含 %q", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+// TestCheckVarsFromResult 覆盖适用于所有 ping 类型的变量
+func TestCheckVarsFromResult(t *testing.T) {
+	env := checkEnv{
+		result:   PingResult{Error: errors.New("boom"), Corrupted: true, BytesSent: 64, BytesRecv: 32},
+		pingType: "icmp",
+		header:   http.Header{},
+	}
+	for _, src := range []string{
+		`!ok && error == "boom" && category == "other"`,
+		"corrupted && bytes_sent == 64 && bytes_received == 32",
+	} {
+		expr, err := compileCheck(src)
+		if err != nil {
+			t.Fatalf("compileCheck(%q): %v", src, err)
+		}
+		if ok, err := expr.Eval(env); err != nil || !ok {
+			t.Errorf("%q = %v, %v, 期望 true", src, ok, err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// clockSkew 根据 Date 响应头估算服务器时钟相对本机的偏差，正值表示服务器时钟偏快。
+// Date 只精确到秒 (截断)，取该秒的中点；服务器生成响应的本机时间取请求往返的中点。
+// 误差约为 ±0.5 秒加上往返时间的一半，因此只适合发现明显的 NTP 问题。
+func clockSkew(header http.Header, start time.Time, rtt time.Duration) (time.Duration, bool) {
+	date := header.Get("Date")
+	if date == "" {
+		return 0, false
+	}
+	t, err := http.ParseTime(date)
+	if err != nil {
+		return 0, false
+	}
+	skew := t.Add(500 * time.Millisecond).Sub(start.Add(rtt / 2))
+	if skew == 0 {
+		skew = 1 // 0 表示没有 Date 头
+	}
+	return skew, true
+}
+
+// checkSkew 校验 -max-skew
+func (o *Options) checkSkew(skew time.Duration) error {
+	if o.MaxSkew <= 0 || skew == 0 || skew.Abs() <= o.MaxSkew {
+		return nil
+	}
+	return fmt.Errorf("服务器时钟比本机%s，超过 -max-skew %v", formatSkew(skew), o.MaxSkew)
+}
+
+// formatSkew 以带方向的文字描述时钟偏差，精确到 0.1 秒
+func formatSkew(skew time.Duration) string {
+	if skew > 0 {
+		return fmt.Sprintf("快 %v", skew.Round(100*time.Millisecond))
+	}
+	return fmt.Sprintf("慢 %v", (-skew).Round(100*time.Millisecond))
+}
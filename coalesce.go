@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// repeatedError 记录某个目标连续出现的同一个错误
+type repeatedError struct {
+	message  string
+	firstSeq int
+	lastSeq  int
+	count    int // 首条之后被折叠的次数
+	lastTime time.Time
+}
+
+// coalescer 实现 -coalesce: 同一目标连续出现相同错误时只输出首条，
+// 错误变化、恢复或运行结束时补一行带重复次数的汇总，保留故障时间线。
+type coalescer struct {
+	last map[string]*repeatedError
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{last: make(map[string]*repeatedError)}
+}
+
+// suppress 判断结果是否与该目标上一条错误相同而应被折叠；
+// 不折叠时先输出之前累计的重复汇总
+func (c *coalescer) suppress(result PingResult, seq int, timestamps bool) bool {
+	prev := c.last[result.label()]
+	if !result.Success && prev != nil && prev.message == result.Error.Error() {
+		prev.lastSeq = seq
+		prev.lastTime = result.Timestamp
+		prev.count++
+		return true
+	}
+
+	c.flushTarget(result.label(), timestamps)
+	if !result.Success {
+		c.last[result.label()] = &repeatedError{message: result.Error.Error(), firstSeq: seq, lastSeq: seq}
+	}
+	return false
+}
+
+// flushTarget 输出某个目标被折叠的重复错误汇总
+func (c *coalescer) flushTarget(target string, timestamps bool) {
+	prev := c.last[target]
+	delete(c.last, target)
+	if prev == nil || prev.count == 0 {
+		return
+	}
+	if timestamps {
+		fmt.Print(prev.lastTime.Format("2006-01-02 15:04:05") + " ")
+	}
+	fmt.Printf("[%d-%d] %s请求失败 %s: 同上错误 x%d%s\n",
+		prev.firstSeq+1, prev.lastSeq, ColorRed, target, prev.count, ColorReset)
+}
+
+// flush 在运行结束时输出所有目标尚未汇总的重复错误
+func (c *coalescer) flush(targets []string, timestamps bool) {
+	for _, t := range targets {
+		c.flushTarget(t, timestamps)
+	}
+}
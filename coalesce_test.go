@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureStdout 返回 fn 执行期间写到标准输出的内容
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+	defer func() { os.Stdout = stdout }()
+	fn()
+	w.Close()
+	return <-done
+}
+
+// TestCoalesceOnlyErrors 确认 -only-errors 时折叠器仍能看到成功结果: 恢复时输出累计的重复次数，
+// 恢复后再次出现的同一错误重新开始计数，而不是并入恢复前的连续错误
+func TestCoalesceOnlyErrors(t *testing.T) {
+	refused := errors.New("connection refused")
+	results := []PingResult{
+		{Target: "a:80", Error: refused},
+		{Target: "a:80", Error: refused},
+		{Target: "a:80", Error: refused},
+		{Target: "a:80", Success: true},
+		{Target: "a:80", Error: refused},
+	}
+	text := &textSink{onlyErrors: true, coalesce: newCoalescer()}
+	out := captureStdout(t, func() {
+		for i, r := range results {
+			text.WriteResult(r, i+1)
+		}
+	})
+	if !strings.Contains(out, "[2-3]") || !strings.Contains(out, "x2") {
+		t.Errorf("恢复时没有输出重复错误汇总:\n%s", out)
+	}
+	if strings.Contains(out, "响应来自") {
+		t.Errorf("-only-errors 时输出了成功结果:\n%s", out)
+	}
+	if n := strings.Count(out, "connection refused"); n != 2 {
+		t.Errorf("恢复后的新错误应单独输出, 共 %d 条错误行:\n%s", n, out)
+	}
+}
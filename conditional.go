@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseIfModifiedSince 解析 -if-modified-since，接受 HTTP 日期或 RFC 3339 时间，
+// 统一转换为 HTTP 日期格式
+func parseIfModifiedSince(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	t, err := http.ParseTime(s)
+	if err != nil {
+		if t, err = time.Parse(time.RFC3339, s); err != nil {
+			return "", fmt.Errorf("无效的时间 %q，应为 HTTP 日期或 RFC 3339 格式", s)
+		}
+	}
+	return t.UTC().Format(http.TimeFormat), nil
+}
+
+// setRequestHeaders 设置所有探测请求共用的请求头: 支持的压缩格式，
+// 以及 -if-none-match 和 -if-modified-since 指定的条件请求头
+func (o *Options) setRequestHeaders(req *http.Request) {
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if o.IfNoneMatch != "" {
+		req.Header.Set("If-None-Match", o.IfNoneMatch)
+	}
+	if o.IfModifiedSince != "" {
+		req.Header.Set("If-Modified-Since", o.IfModifiedSince)
+	}
+}
+
+// conditional 判断请求是否带有条件请求头
+func (o *Options) conditional() bool {
+	return o.IfNoneMatch != "" || o.IfModifiedSince != ""
+}
+
+// checkExpectStatus 校验 -expect-status；发送了条件请求头却没有得到 304 时说明服务器未遵循缓存验证
+func (o *Options) checkExpectStatus(code int) error {
+	if o.ExpectStatus[code] {
+		return nil
+	}
+	if o.conditional() && o.ExpectStatus[http.StatusNotModified] && code >= 200 && code < 300 {
+		return fmt.Errorf("服务器未遵循缓存验证: 期望 304，实际返回 %d (完整响应)", code)
+	}
+	return fmt.Errorf("状态码 %d 不在期望的 %s 中", code, formatStatusList(o.ExpectStatus))
+}
+
+// formatStatusList 按升序输出状态码集合，用于错误信息
+func formatStatusList(statuses map[int]bool) string {
+	codes := make([]int, 0, len(statuses))
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		parts[i] = strconv.Itoa(code)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config 是 -config 指定的 YAML 配置文件 (JSON 也是合法的 YAML)
+type config struct {
+	Targets  []configTarget           `yaml:"targets"`
+	Profiles map[string]configProfile `yaml:"profiles"` // 命名的环境 (如 dev/staging/prod)，用 -profile 选择
+}
+
+// configProfile 是配置文件中的一个命名环境
+type configProfile struct {
+	Targets []configTarget `yaml:"targets"`
+}
+
+// configTarget 是配置文件中的单个目标及其 SLA 规则
+type configTarget struct {
+	Target string    `yaml:"target"`
+	Name   string    `yaml:"name"` // 检查名称，同一目标可以用不同名称配置多个检查
+	Rules  []slaRule `yaml:"rules"`
+}
+
+// loadConfig 读取并校验配置文件，未知字段视为错误以免拼写错误被静默忽略
+func loadConfig(path string) (*config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var cfg config
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, err
+	}
+
+	if err := validateTargets(cfg.Targets); err != nil {
+		return nil, err
+	}
+	for name, p := range cfg.Profiles {
+		if err := validateTargets(p.Targets); err != nil {
+			return nil, fmt.Errorf("profile %s: %v", name, err)
+		}
+	}
+	return &cfg, nil
+}
+
+func validateTargets(targets []configTarget) error {
+	for i, t := range targets {
+		if t.Target == "" {
+			return fmt.Errorf("第 %d 个目标缺少 target", i+1)
+		}
+		for j := range t.Rules {
+			if err := t.Rules[j].validate(); err != nil {
+				return fmt.Errorf("目标 %s: %v", t.Target, err)
+			}
+		}
+	}
+	return nil
+}
+
+// selectTargets 返回要运行的目标: 指定 profile 时只使用该 profile 的目标，
+// 否则使用顶层 targets。文件只定义了 profiles 时必须用 -profile 选择一个。
+func (c *config) selectTargets(profile string) ([]configTarget, error) {
+	names := slices.Sorted(maps.Keys(c.Profiles))
+	if profile == "" {
+		if len(c.Targets) == 0 && len(c.Profiles) > 0 {
+			return nil, fmt.Errorf("配置文件只定义了 profiles，请用 -profile 选择: %s", strings.Join(names, ", "))
+		}
+		return c.Targets, nil
+	}
+	p, ok := c.Profiles[profile]
+	if !ok {
+		if len(names) == 0 {
+			return nil, fmt.Errorf("profile %q 不存在，配置文件没有定义 profiles", profile)
+		}
+		return nil, fmt.Errorf("profile %q 不存在，可选: %s", profile, strings.Join(names, ", "))
+	}
+	return p.Targets, nil
+}
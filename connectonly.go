@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// pingConnectOnly 实现 -connect-only: 建立 HTTP 连接 (HTTPS 完成 TLS 握手) 后立即取消请求，
+// 不发送请求也不等待服务端处理，结果时间只包含 DNS、TCP 连接和 TLS 握手
+func pingConnectOnly(target string, opts *Options) (result PingResult) {
+	result.Target = target
+	if opts.Script != nil {
+		defer func() {
+			applyScript(opts.Script, checkEnv{result: result, pingType: opts.PingType, header: http.Header{}}, &result)
+		}()
+	}
+
+	req, err := newHTTPRequest(httpURL(target, opts), opts)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	if opts.Port != "" && req.URL.Port() == "" {
+		req.URL.Host = net.JoinHostPort(req.URL.Hostname(), opts.Port)
+		req.Host = req.URL.Host
+	}
+	https := req.URL.Scheme == "https"
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	var start time.Time
+	var done time.Duration // 连接就绪的时间，0 表示没有完成
+	var state *tls.ConnectionState
+	trace := &httptrace.ClientTrace{
+		ConnectDone: func(network, addr string, err error) {
+			if err != nil {
+				return
+			}
+			result.RemoteAddr = addr
+			if !https {
+				done = time.Since(start)
+				cancel()
+			}
+		},
+		TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
+			if err != nil {
+				return
+			}
+			done = time.Since(start)
+			state = &cs
+			cancel()
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(ctx, trace))
+
+	// 连接只用一次，不能复用 -keepalive 的共享连接池，否则不会重新握手
+	transport := newHTTPTransport(opts)
+	defer transport.CloseIdleConnections()
+
+	start = time.Now()
+	resp, err := transport.RoundTrip(req)
+	result.ResponseTime = time.Since(start)
+	if err == nil {
+		resp.Body.Close() // 取消之前请求已经完成，只在连接回调没有触发时出现
+	}
+	if done == 0 {
+		if err == nil {
+			err = errors.New("没有观察到连接建立")
+		}
+		if opts.DumpChain {
+			result.Chain = inspectChain(presentedChain(nil, err), opts.TLSVerify.roots, time.Now())
+		}
+		result.Error = explainReset(err)
+		return result
+	}
+
+	result.ResponseTime = done
+	if state != nil && len(state.PeerCertificates) > 0 {
+		result.CertNotAfter = state.PeerCertificates[0].NotAfter
+		result.TLSReport = opts.TLSVerify.report(state, req.URL.Hostname())
+		if opts.DumpChain {
+			result.Chain = inspectChain(state.PeerCertificates, opts.TLSVerify.roots, time.Now())
+		}
+	}
+	if len(opts.Pins) > 0 {
+		if err := opts.Pins.check(state); err != nil {
+			result.Error = err
+			return result
+		}
+	}
+	result.Success = true
+	return result
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// csvHeader 是 CSV 输出的列，每次 ping 一行
+var csvHeader = []string{"timestamp", "seq", "target", "success", "response_time_ms", "status_code", "error", "retries", "bytes_sent", "bytes_received", "name"}
+
+// csvSink 以 CSV 形式逐行输出结果，不包含统计信息。-tag 标签作为额外的列放在最后。
+type csvSink struct {
+	f    *os.File
+	w    *csv.Writer
+	tags tagFlag
+}
+
+func init() {
+	registerSink("csv", sinkFormat{
+		create: fileSink(func(f *os.File, tags tagFlag) OutputSink { return newCSVSink(f, tags) }),
+	})
+}
+
+func newCSVSink(f *os.File, tags tagFlag) *csvSink {
+	return &csvSink{f: f, w: csv.NewWriter(f), tags: tags}
+}
+
+func (c *csvSink) WriteHeader([]string, string) error {
+	header := slices.Clone(csvHeader)
+	for _, t := range c.tags {
+		header = append(header, t.key)
+	}
+	c.w.Write(header)
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvSink) WriteResult(result PingResult, seq int) error {
+	errText := ""
+	if result.Error != nil {
+		errText = result.Error.Error()
+	}
+	record := []string{
+		result.Timestamp.Format(time.RFC3339Nano),
+		strconv.Itoa(seq),
+		result.Target,
+		strconv.FormatBool(result.Success),
+		strconv.FormatFloat(durationMs(result.ResponseTime), 'f', 3, 64),
+		strconv.Itoa(result.StatusCode),
+		errText,
+		strconv.Itoa(result.Retries),
+		strconv.FormatInt(result.BytesSent, 10),
+		strconv.FormatInt(result.BytesRecv, 10),
+		result.Name,
+	}
+	for _, t := range c.tags {
+		record = append(record, t.value)
+	}
+	c.w.Write(record)
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvSink) EndRound([]PingResult, int) error { return nil }
+
+func (c *csvSink) WriteSummary([]string, []*targetStats) error { return nil }
+
+func (c *csvSink) Close() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		closeOutputFile(c.f)
+		return err
+	}
+	return closeOutputFile(c.f)
+}
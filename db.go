@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// MySQL 能力标志中与握手探测有关的位
+const (
+	mysqlClientProtocol41 = 0x00000200
+	mysqlClientSSL        = 0x00000800
+)
+
+// postgresSSLRequest 是 PostgreSQL SSLRequest 消息中的协议号
+const postgresSSLRequest = 80877103
+
+// dbSession 是一次数据库握手探测，TLS 升级后 conn 替换为加密连接
+type dbSession struct {
+	conn   net.Conn
+	host   string
+	opts   *Options
+	banner string
+	tls    *tls.ConnectionState
+}
+
+// startTLS 在当前连接上完成 TLS 握手
+func (s *dbSession) startTLS() error {
+	tc := tls.Client(s.conn, s.opts.TLSVerify.clientConfig(s.host))
+	if err := tc.Handshake(); err != nil {
+		return fmt.Errorf("TLS 握手失败: %w", err)
+	}
+	state := tc.ConnectionState()
+	s.tls = &state
+	s.conn = tc
+	return nil
+}
+
+// readMySQLPacket 读取一个 MySQL 报文: 3 字节小端长度 + 1 字节序号 + 负载
+func readMySQLPacket(r io.Reader) (payload []byte, seq byte, err error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, 0, explainReset(err)
+	}
+	n := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, 0, explainReset(err)
+	}
+	return payload, header[3], nil
+}
+
+// mysqlSession 读取服务器的初始握手报文 (协议版本 10)，不进行认证。
+// 服务器拒绝连接时 (如主机不在授权列表、连接数已满) 发送的是错误报文。
+// 启用 -starttls 时回复 SSLRequest 并完成 TLS 握手。
+func mysqlSession(s *dbSession) error {
+	payload, seq, err := readMySQLPacket(s.conn)
+	if err != nil {
+		return fmt.Errorf("读取握手报文: %w", err)
+	}
+	if len(payload) > 0 && payload[0] == 0xff {
+		return mysqlError(payload)
+	}
+	if len(payload) < 1 || payload[0] != 10 {
+		return errors.New("不是 MySQL 握手报文 (协议版本应为 10)")
+	}
+	version, rest, ok := bytes.Cut(payload[1:], []byte{0})
+	// 版本之后依次是 4 字节连接 ID、8 字节随机数、1 字节填充和能力标志的低 2 字节
+	if !ok || len(rest) < 15 {
+		return errors.New("MySQL 握手报文不完整")
+	}
+	s.banner = "MySQL " + string(version)
+	caps := uint32(binary.LittleEndian.Uint16(rest[13:15]))
+
+	if !s.opts.StartTLS {
+		return nil
+	}
+	if caps&mysqlClientSSL == 0 {
+		return errors.New("服务器不支持 SSL")
+	}
+	// SSLRequest: 能力标志、最大报文长度、字符集 (utf8mb4) 和 23 字节保留
+	req := make([]byte, 4+32)
+	req[0], req[3] = 32, seq+1
+	binary.LittleEndian.PutUint32(req[4:], mysqlClientProtocol41|mysqlClientSSL)
+	binary.LittleEndian.PutUint32(req[8:], 1<<24)
+	req[12] = 45
+	if _, err := s.conn.Write(req); err != nil {
+		return explainReset(err)
+	}
+	return s.startTLS()
+}
+
+// mysqlError 解析 MySQL 错误报文: 0xff、2 字节错误码、可选的 # 和 5 字节 SQLSTATE、错误信息
+func mysqlError(payload []byte) error {
+	if len(payload) < 3 {
+		return errors.New("MySQL 错误报文不完整")
+	}
+	code := binary.LittleEndian.Uint16(payload[1:3])
+	msg := payload[3:]
+	if len(msg) >= 6 && msg[0] == '#' {
+		msg = msg[6:]
+	}
+	return fmt.Errorf("MySQL 拒绝连接 (错误 %d): %s", code, msg)
+}
+
+// postgresAuthMethods 是 AuthenticationRequest 中的认证方式
+var postgresAuthMethods = map[uint32]string{
+	0:  "无需密码",
+	3:  "明文密码",
+	5:  "MD5",
+	7:  "GSSAPI",
+	9:  "SSPI",
+	10: "SASL",
+}
+
+// postgresSession 发送启动消息并等待服务器的认证请求，不进行认证。
+// 启用 -starttls 时先发送 SSLRequest，服务器同意 (S) 后完成 TLS 握手。
+func postgresSession(s *dbSession) error {
+	if s.opts.StartTLS {
+		req := binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, 8), postgresSSLRequest)
+		if _, err := s.conn.Write(req); err != nil {
+			return explainReset(err)
+		}
+		var answer [1]byte
+		if _, err := io.ReadFull(s.conn, answer[:]); err != nil {
+			return fmt.Errorf("SSLRequest: %w", explainReset(err))
+		}
+		if answer[0] != 'S' {
+			return errors.New("服务器不支持 SSL")
+		}
+		if err := s.startTLS(); err != nil {
+			return err
+		}
+	}
+
+	// StartupMessage: 长度、协议版本 3.0，以及以 \0 分隔的参数
+	user := s.opts.PostgresUser
+	body := binary.BigEndian.AppendUint32(nil, 3<<16)
+	for _, kv := range [][2]string{{"user", user}, {"database", user}, {"application_name", "ping-tool"}} {
+		body = append(append(append(append(body, kv[0]...), 0), kv[1]...), 0)
+	}
+	body = append(body, 0)
+	msg := binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))
+	if _, err := s.conn.Write(append(msg, body...)); err != nil {
+		return explainReset(err)
+	}
+
+	var header [5]byte
+	if _, err := io.ReadFull(s.conn, header[:]); err != nil {
+		return fmt.Errorf("读取启动回复: %w", explainReset(err))
+	}
+	n := int(binary.BigEndian.Uint32(header[1:])) - 4
+	if n < 0 || n > 1<<16 {
+		return errors.New("不是 PostgreSQL 回复")
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(s.conn, payload); err != nil {
+		return fmt.Errorf("读取启动回复: %w", explainReset(err))
+	}
+	switch header[0] {
+	case 'R':
+		if len(payload) < 4 {
+			return errors.New("PostgreSQL 认证请求不完整")
+		}
+		code := binary.BigEndian.Uint32(payload)
+		method, ok := postgresAuthMethods[code]
+		if !ok {
+			method = fmt.Sprintf("类型 %d", code)
+		}
+		// SASL 之后是以 \0 分隔的机制列表，如 SCRAM-SHA-256
+		if mechs := strings.Fields(strings.ReplaceAll(string(payload[4:]), "\x00", " ")); code == 10 && len(mechs) > 0 {
+			method += " (" + strings.Join(mechs, ", ") + ")"
+		}
+		s.banner = "PostgreSQL，认证方式 " + method
+		// 关闭前发送 Terminate，避免服务器日志中出现意外断开的记录
+		s.conn.Write([]byte{'X', 0, 0, 0, 4})
+		return nil
+	case 'E':
+		return postgresError(payload)
+	}
+	return fmt.Errorf("不是 PostgreSQL 认证请求 (消息类型 %q)", header[0])
+}
+
+// postgresError 从 ErrorResponse 的字段中取出严重级别、SQLSTATE 和错误信息
+func postgresError(payload []byte) error {
+	fields := make(map[byte]string)
+	for len(payload) > 1 {
+		value, rest, _ := bytes.Cut(payload[1:], []byte{0})
+		fields[payload[0]] = string(value)
+		payload = rest
+	}
+	return fmt.Errorf("PostgreSQL 拒绝连接 (%s %s): %s", fields['S'], fields['C'], fields['M'])
+}
+
+// dbSessions 是各数据库 ping 类型的握手实现
+var dbSessions = map[string]func(*dbSession) error{
+	"mysql":    mysqlSession,
+	"postgres": postgresSession,
+}
+
+// pingDB 完成数据库协议的初始握手 (不认证)，确认数据库进程正在接受连接，
+// 而不只是端口开放。响应时间包含连接、握手和可选的 TLS 升级。
+func pingDB(target string, opts *Options) PingResult {
+	result := PingResult{Target: target}
+	session := dbSessions[strings.ToLower(opts.PingType)]
+
+	target = withDefaultPort(target, opts.defaultPort())
+	host, _, err := net.SplitHostPort(target)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	start := time.Now()
+	raw, err := opts.dial("tcp", target)
+	if err != nil {
+		result.ResponseTime = time.Since(start)
+		result.Error = err
+		return result
+	}
+	conn := &countingConn{Conn: raw}
+	defer conn.Close()
+	result.RemoteAddr = conn.RemoteAddr().String()
+	if err := conn.SetDeadline(start.Add(opts.Timeout)); err != nil {
+		result.Error = err
+		return result
+	}
+
+	s := &dbSession{conn: conn, host: host, opts: opts}
+	err = session(s)
+	result.ResponseTime = time.Since(start)
+	result.Banner = s.banner
+	result.BytesSent, result.BytesRecv = conn.sent.Load(), conn.received.Load()
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	if s.tls != nil && len(s.tls.PeerCertificates) > 0 {
+		result.CertNotAfter = s.tls.PeerCertificates[0].NotAfter
+		result.TLSReport = opts.TLSVerify.report(s.tls, host)
+	}
+	if len(opts.Pins) > 0 {
+		if err := opts.Pins.check(s.tls); err != nil {
+			result.Error = err
+			return result
+		}
+	}
+	result.Success = true
+	return result
+}
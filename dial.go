@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// resolveFlag 实现可重复的 -resolve 参数，格式与 curl 相同:
+// host:port:addr 仅覆盖指定端口，host:addr 覆盖该主机的所有端口。
+// IPv6 地址必须写在方括号中 (host:port:[2001:db8::1])，否则无法与端口区分。
+type resolveFlag map[string]string
+
+func (r resolveFlag) String() string {
+	entries := make([]string, 0, len(r))
+	for key, addr := range r {
+		entries = append(entries, key+":"+addr)
+	}
+	return strings.Join(entries, ",")
+}
+
+func (r resolveFlag) Set(value string) error {
+	host, rest, ok := strings.Cut(value, ":")
+	if !ok || host == "" || rest == "" {
+		return fmt.Errorf("格式应为 host:port:addr 或 host:addr")
+	}
+
+	key := host
+	if port, addr, ok := strings.Cut(rest, ":"); ok && !strings.HasPrefix(rest, "[") {
+		n, err := strconv.Atoi(port)
+		switch {
+		case err == nil && n > 0 && n <= 65535:
+			key = net.JoinHostPort(host, port)
+			rest = addr
+		case strings.HasPrefix(addr, "[") || net.ParseIP(addr).To4() != nil:
+			// 后面是合法地址，说明中间一段本应是端口
+			return fmt.Errorf("无效的端口 %q", port)
+		}
+	}
+
+	addr := rest
+	if strings.HasPrefix(rest, "[") && strings.HasSuffix(rest, "]") {
+		addr = rest[1 : len(rest)-1]
+		if ip := net.ParseIP(addr); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("无效的 IPv6 地址 %q", rest)
+		}
+	} else if strings.Contains(rest, ":") {
+		return fmt.Errorf("IPv6 地址需要写在方括号中，如 %s:443:[2001:db8::1]", host)
+	}
+	if net.ParseIP(addr) == nil {
+		return fmt.Errorf("无效的 IP 地址 %q", rest)
+	}
+	r[strings.ToLower(key)] = addr
+	return nil
+}
+
+// lookup 返回 host:port 对应的覆盖地址，精确匹配端口优先
+func (r resolveFlag) lookup(host, port string) (string, bool) {
+	host = strings.ToLower(host)
+	if addr, ok := r[net.JoinHostPort(host, port)]; ok {
+		return addr, true
+	}
+	addr, ok := r[host]
+	return addr, ok
+}
+
+// overrideAddr 返回 host 的覆盖地址，优先级: -resolve > -hostfile > 正常 DNS 解析。
+// port 为空时只匹配不区分端口的覆盖 (用于 ICMP)。
+func (o *Options) overrideAddr(host, port string) (string, bool) {
+	if addr, ok := o.Resolve.lookup(host, port); ok {
+		return addr, true
+	}
+	addr, ok := o.Hosts[strings.ToLower(strings.TrimSuffix(host, "."))]
+	return addr, ok
+}
+
+// dialContext 是所有 ping 类型共用的拨号入口，在此应用 -resolve 和 -hostfile 覆盖。
+// 只替换实际连接的地址，HTTP 的 Host 头和 TLS SNI 仍使用原始主机名。
+// 指定 -ssh 时连接通过跳板机转发，域名也由跳板机解析；-proxy-list 时经由本次轮换到的代理连接。
+// -resolve-family 只影响直接连接: 4/6 限定协议族，dual 使用 Happy Eyeballs 竞速。
+func (o *Options) dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if host, port, err := net.SplitHostPort(address); err == nil {
+		if addr, ok := o.overrideAddr(host, port); ok {
+			address = net.JoinHostPort(addr, port)
+		}
+	}
+
+	if o.proxy != nil {
+		return o.proxy.dialContext(ctx, o.Timeout, address)
+	}
+
+	if o.SSH != nil {
+		ctx, cancel := context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+		return o.SSH.DialContext(ctx, network, address)
+	}
+
+	switch {
+	case o.Family == "dual" && network == "tcp":
+		return o.dialHappyEyeballs(ctx, network, address)
+	case (o.Family == "4" || o.Family == "6") && (network == "tcp" || network == "udp"):
+		network += o.Family
+	}
+	dialer := &net.Dialer{Timeout: o.Timeout}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// dial 是不带 context 的 dialContext 简便封装，超时由 -timeout 控制
+func (o *Options) dial(network, address string) (net.Conn, error) {
+	return o.dialContext(context.Background(), network, address)
+}
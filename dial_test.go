@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveFlagSet(t *testing.T) {
+	tests := []struct {
+		value   string
+		key     string // 期望写入的键
+		addr    string // 期望的覆盖地址
+		wantErr string // 为空时期望解析成功
+	}{
+		{value: "example.com:443:1.2.3.4", key: "example.com:443", addr: "1.2.3.4"},
+		{value: "Example.COM:80:1.2.3.4", key: "example.com:80", addr: "1.2.3.4"},
+		{value: "example.com:1.2.3.4", key: "example.com", addr: "1.2.3.4"},
+		{value: "example.com:443:[2001:db8::1]", key: "example.com:443", addr: "2001:db8::1"},
+		{value: "example.com:[2001:db8::1]", key: "example.com", addr: "2001:db8::1"},
+		{value: "example.com:[::1]", key: "example.com", addr: "::1"},
+		{value: "example.com:2001:db8::1", wantErr: "IPv6 地址需要写在方括号中"},
+		{value: "example.com:443:2001:db8::1", wantErr: "IPv6 地址需要写在方括号中"},
+		{value: "example.com:fe80::1", wantErr: "IPv6 地址需要写在方括号中"},
+		{value: "example.com:[1.2.3.4]", wantErr: "无效的 IPv6 地址"},
+		{value: "example.com:[2001:db8::zz]", wantErr: "无效的 IPv6 地址"},
+		{value: "example.com:abc:1.2.3.4", wantErr: `无效的端口 "abc"`},
+		{value: "example.com:0:1.2.3.4", wantErr: `无效的端口 "0"`},
+		{value: "example.com:65536:1.2.3.4", wantErr: `无效的端口 "65536"`},
+		{value: "example.com:-1:[::1]", wantErr: `无效的端口 "-1"`},
+		{value: "example.com:443:", wantErr: "无效的 IP 地址"},
+		{value: "example.com:not-an-ip", wantErr: "无效的 IP 地址"},
+		{value: "example.com", wantErr: "格式应为"},
+		{value: ":1.2.3.4", wantErr: "格式应为"},
+		{value: "example.com:", wantErr: "格式应为"},
+	}
+	for _, tt := range tests {
+		r := resolveFlag{}
+		err := r.Set(tt.value)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Set(%q) 错误 = %v, 期望包含 %q", tt.value, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Set(%q) 失败: %v", tt.value, err)
+			continue
+		}
+		if got, ok := r[tt.key]; !ok || got != tt.addr || len(r) != 1 {
+			t.Errorf("Set(%q) 后 = %v, 期望 %s -> %s", tt.value, r, tt.key, tt.addr)
+		}
+	}
+}
+
+func TestResolveFlagLookup(t *testing.T) {
+	r := resolveFlag{}
+	for _, v := range []string{"example.com:1.1.1.1", "example.com:443:[2001:db8::1]"} {
+		if err := r.Set(v); err != nil {
+			t.Fatalf("Set(%q): %v", v, err)
+		}
+	}
+	tests := []struct {
+		host, port string
+		want       string
+		ok         bool
+	}{
+		{"example.com", "443", "2001:db8::1", true},
+		{"EXAMPLE.com", "443", "2001:db8::1", true},
+		{"example.com", "80", "1.1.1.1", true},
+		{"example.com", "", "1.1.1.1", true},
+		{"other.com", "443", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := r.lookup(tt.host, tt.port)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("lookup(%q, %q) = %q, %v, 期望 %q, %v", tt.host, tt.port, got, ok, tt.want, tt.ok)
+		}
+	}
+}
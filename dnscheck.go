@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// -strict-dns 区分的 DNS 失败类别，同时作为错误分类
+const (
+	errCategoryNXDomain = "nxdomain" // 域名不存在
+	errCategoryServFail = "servfail" // 权威或递归服务器解析失败
+	errCategoryNoData   = "nodata"   // 域名存在但没有 A/AAAA 记录
+	errCategoryCNAME    = "cname"    // 只有 CNAME，链条最终没有指向地址
+)
+
+// dnsCategories 是 -strict-dns 失败类别在统计中的显示顺序和说明
+var dnsCategories = []struct{ key, label string }{
+	{errCategoryNXDomain, "NXDOMAIN"},
+	{errCategoryServFail, "SERVFAIL"},
+	{errCategoryNoData, "空应答"},
+	{errCategoryCNAME, "只有 CNAME"},
+}
+
+// dnsCheckError 是 -strict-dns 判定的 DNS 失败
+type dnsCheckError struct {
+	category string
+	msg      string
+}
+
+func (e *dnsCheckError) Error() string { return e.msg }
+
+// dnsServer 返回 -strict-dns 查询的服务器: -dns-server 优先，否则使用 /etc/resolv.conf 的第一个 nameserver
+func dnsServer(server string) string {
+	if server != "" {
+		return withDefaultPort(server, "53")
+	}
+	if f, err := os.Open("/etc/resolv.conf"); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" {
+				return net.JoinHostPort(fields[1], "53")
+			}
+		}
+	}
+	return "127.0.0.1:53"
+}
+
+// strictDNSHost 返回 -strict-dns 需要检查的主机名，IP 目标和被 -resolve/-hostfile 覆盖的主机名返回空
+func strictDNSHost(target string, opts *Options) string {
+	switch strings.ToLower(opts.PingType) {
+	case "http", "https":
+		target = httpURL(target, opts)
+	case "ws", "wss":
+		target = wsURL(target, opts)
+	}
+	host, port := targetHost(target, opts)
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	if _, ok := opts.overrideAddr(host, port); ok {
+		return ""
+	}
+	return host
+}
+
+// checkDNS 实现 -strict-dns: 直接向 DNS 服务器查询 A 和 AAAA 记录，把 NXDOMAIN、SERVFAIL、
+// 空应答和只有 CNAME 的应答分别报告为失败。成功时返回应答中的 CNAME 链
+func checkDNS(host, server string, timeout time.Duration) ([]string, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, err
+	}
+	var cnames []string
+	addrs := 0
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		msg, err := dnsQuery(name, qtype, server, timeout)
+		if err != nil {
+			return nil, err
+		}
+		switch msg.RCode {
+		case dnsmessage.RCodeSuccess:
+		case dnsmessage.RCodeNameError:
+			return nil, &dnsCheckError{errCategoryNXDomain, fmt.Sprintf("DNS: %s 不存在 (NXDOMAIN)", host)}
+		case dnsmessage.RCodeServerFailure:
+			return nil, &dnsCheckError{errCategoryServFail, fmt.Sprintf("DNS: 服务器 %s 解析 %s 失败 (SERVFAIL)", server, host)}
+		default:
+			return nil, fmt.Errorf("DNS: 服务器 %s 返回 %v", server, msg.RCode)
+		}
+		for _, ans := range msg.Answers {
+			switch body := ans.Body.(type) {
+			case *dnsmessage.AResource, *dnsmessage.AAAAResource:
+				addrs++
+			case *dnsmessage.CNAMEResource:
+				if target := body.CNAME.String(); !slices.Contains(cnames, target) {
+					cnames = append(cnames, target)
+				}
+			}
+		}
+	}
+	switch {
+	case addrs > 0:
+		return cnames, nil
+	case len(cnames) > 0:
+		return cnames, &dnsCheckError{errCategoryCNAME, fmt.Sprintf("DNS: %s 只有 CNAME (%s)，没有解析到地址", host, strings.Join(cnames, " → "))}
+	}
+	return nil, &dnsCheckError{errCategoryNoData, fmt.Sprintf("DNS: %s 没有 A/AAAA 记录 (空应答)", host)}
+}
+
+// dnsQuery 通过 UDP 发送一个递归查询，应答被截断时改用 TCP 重新查询
+func dnsQuery(name dnsmessage.Name, qtype dnsmessage.Type, server string, timeout time.Duration) (*dnsmessage.Message, error) {
+	id := uint16(rand.Uint32())
+	query, err := (&dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}).Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("udp", server, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 1232)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("DNS: 查询 %s: %w", server, err)
+		}
+		var msg dnsmessage.Message
+		if err := msg.Unpack(buf[:n]); err != nil || msg.ID != id || !msg.Response {
+			continue // 不是本次查询的应答
+		}
+		if msg.Truncated {
+			return dnsQueryTCP(query, server, timeout)
+		}
+		return &msg, nil
+	}
+}
+
+// dnsQueryTCP 通过 TCP 发送查询，报文前加两字节长度
+func dnsQueryTCP(query []byte, server string, timeout time.Duration) (*dnsmessage.Message, error) {
+	conn, err := net.DialTimeout("tcp", server, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(query))), query...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, fmt.Errorf("DNS: 查询 %s: %w", server, err)
+	}
+	reply := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, fmt.Errorf("DNS: 查询 %s: %w", server, err)
+	}
+	var msg dnsmessage.Message
+	if err := msg.Unpack(reply); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// isDNSCategory 判断错误分类是否属于 -strict-dns 的失败类别
+func isDNSCategory(category string) bool {
+	return slices.ContainsFunc(dnsCategories, func(c struct{ key, label string }) bool { return c.key == category })
+}
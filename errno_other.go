@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+import "syscall"
+
+// 连接被拒绝、连接中断和本地地址不可用对应的系统错误码
+var (
+	connRefusedErrnos  = []syscall.Errno{syscall.ECONNREFUSED}
+	connResetErrnos    = []syscall.Errno{syscall.ECONNRESET, syscall.ECONNABORTED, syscall.EPIPE}
+	addrNotAvailErrnos = []syscall.Errno{syscall.EADDRNOTAVAIL}
+)
@@ -0,0 +1,18 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+// Windows 的网络调用返回 Winsock 错误码 (WSAE*)，与 syscall 中模拟的 POSIX 错误码不相等，两者都要匹配
+const (
+	wsaeConnRefused  syscall.Errno = 10061
+	wsaeAddrNotAvail syscall.Errno = 10049
+)
+
+// 连接被拒绝、连接中断和本地地址不可用对应的系统错误码
+var (
+	connRefusedErrnos  = []syscall.Errno{syscall.ECONNREFUSED, wsaeConnRefused}
+	connResetErrnos    = []syscall.Errno{syscall.ECONNRESET, syscall.ECONNABORTED, syscall.EPIPE, syscall.WSAECONNRESET, syscall.WSAECONNABORTED}
+	addrNotAvailErrnos = []syscall.Errno{syscall.EADDRNOTAVAIL, wsaeAddrNotAvail}
+)
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// 错误类别，用于在输出中快速区分失败原因
+const (
+	errCategoryTimeout = "timeout"
+	errCategoryRefused = "refused"
+	errCategoryDNS     = "dns"
+	errCategoryTLS     = "tls"
+	errCategoryReset   = "reset"
+	errCategoryPorts   = "ports" // 本地临时端口耗尽
+	errCategoryOther   = "other"
+)
+
+// classifyError 根据错误链判断失败类别，nil 返回空字符串
+func classifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+	var replayed *replayedError
+	if errors.As(err, &replayed) {
+		return replayed.category
+	}
+
+	var checkErr *dnsCheckError
+	if errors.As(err, &checkErr) {
+		return checkErr.category
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return errCategoryDNS
+	}
+	if isTLSError(err) {
+		return errCategoryTLS
+	}
+
+	var netErr net.Error
+	if errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return errCategoryTimeout
+	}
+	if isErrno(err, connRefusedErrnos) {
+		return errCategoryRefused
+	}
+	if isErrno(err, addrNotAvailErrnos) {
+		return errCategoryPorts
+	}
+	if isConnReset(err) {
+		return errCategoryReset
+	}
+	return errCategoryOther
+}
+
+// isConnReset 判断连接是否在通信中途被对端重置或关闭，包括读到一半的响应和回复
+func isConnReset(err error) bool {
+	return isErrno(err, connResetErrnos) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// isErrno 判断错误链中是否包含 errnos 中的任一系统错误码，各平台的错误码见 errno_*.go
+func isErrno(err error, errnos []syscall.Errno) bool {
+	for _, errno := range errnos {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTLSError(err error) bool {
+	var (
+		recordErr    tls.RecordHeaderError
+		alertErr     tls.AlertError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	switch {
+	case errors.As(err, &recordErr), errors.As(err, &alertErr), errors.As(err, &verifyErr),
+		errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return true
+	}
+	// 握手超时等错误没有专门的类型，只能根据错误信息判断
+	return strings.Contains(err.Error(), "tls: ")
+}
+
+// resetError 在连接中断的底层错误前加上易读的说明，保留原错误链用于分类
+type resetError struct {
+	msg string
+	err error
+}
+
+func (e *resetError) Error() string {
+	if e.err == nil {
+		return e.msg
+	}
+	return e.msg + ": " + e.err.Error()
+}
+func (e *resetError) Unwrap() error { return e.err }
+
+// explainReset 把连接被重置或提前关闭的错误改写为 resetError，其他错误原样返回
+func explainReset(err error) error {
+	var re *resetError
+	if !isConnReset(err) || errors.As(err, &re) {
+		return err
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &resetError{msg: "连接被对端提前关闭", err: err}
+	}
+	return &resetError{msg: "连接被对端重置", err: err}
+}
+
+// portExhaustedError 说明连接失败是因为本地临时端口耗尽，保留原错误链用于分类
+type portExhaustedError struct {
+	err error
+}
+
+func (e *portExhaustedError) Error() string {
+	return "本地临时端口耗尽 (" + e.err.Error() + ")，大量连接处于 TIME_WAIT 时可降低探测频率或使用 -linger 0"
+}
+
+func (e *portExhaustedError) Unwrap() error { return e.err }
+
+// explainPortExhaustion 把本地没有可用临时端口 (EADDRNOTAVAIL) 的拨号错误改写为 portExhaustedError，
+// 其他错误原样返回
+func explainPortExhaustion(err error) error {
+	if !isErrno(err, addrNotAvailErrnos) {
+		return err
+	}
+	return &portExhaustedError{err: err}
+}
+
+// errorTag 返回显示在失败信息前的类别标签，如 [TIMEOUT]；未知类别不显示
+func errorTag(err error) string {
+	category := classifyError(err)
+	if category == "" || category == errCategoryOther {
+		return ""
+	}
+	return "[" + strings.ToUpper(category) + "] "
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resetServer 启动一个假的 HTTP 服务器: 读完请求后发送 partial，然后以 SO_LINGER=0 关闭连接，
+// 内核发送 RST 而不是 FIN
+func resetServer(t *testing.T, partial string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				if _, err := http.ReadRequest(bufio.NewReader(conn)); err != nil {
+					conn.Close()
+					return
+				}
+				conn.Write([]byte(partial))
+				time.Sleep(50 * time.Millisecond) // 确保已发送的部分先到达客户端
+				conn.(*net.TCPConn).SetLinger(0)
+				conn.Close()
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// TestHTTPConnReset 确认响应中途被重置的请求报告为 reset 类别，并带有易读的说明
+func TestHTTPConnReset(t *testing.T) {
+	tests := []struct {
+		name       string
+		partial    string
+		verifyBody bool
+		wantMsg    string
+	}{
+		// net/textproto 读响应头时把读取错误报告为 EOF，RST 只能表现为提前关闭
+		{"响应头中途重置", "HTTP/1.1 200 OK\r\nContent-Length: 100\r\n", false, "连接被对端提前关闭"},
+		{"响应体中途重置", "HTTP/1.1 200 OK\r\nContent-Length: 100\r\n\r\npartial", true, "连接被对端重置"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := resetServer(t, tt.partial)
+			opts := &Options{
+				PingType:   "http",
+				Timeout:    2 * time.Second,
+				Method:     http.MethodGet,
+				ReadBody:   readBodyHeaders,
+				VerifyBody: tt.verifyBody,
+			}
+			result := pingHTTP("http://"+addr+"/", opts)
+			if result.Success || result.Error == nil {
+				t.Fatalf("期望失败，得到 %+v", result)
+			}
+			if got := classifyError(result.Error); got != errCategoryReset {
+				t.Errorf("错误类别 = %q, 期望 %q (错误: %v)", got, errCategoryReset, result.Error)
+			}
+			var re *resetError
+			if !errors.As(result.Error, &re) {
+				t.Fatalf("错误没有经过 explainReset 改写: %v", result.Error)
+			}
+			if re.msg != tt.wantMsg {
+				t.Errorf("说明 = %q, 期望 %q (%v)", re.msg, tt.wantMsg, re.err)
+			}
+			if msg := result.Error.Error(); !strings.Contains(msg, tt.wantMsg) || !strings.Contains(msg, addr) {
+				t.Errorf("错误信息 %q 应同时包含说明和地址 %s", msg, addr)
+			}
+		})
+	}
+}
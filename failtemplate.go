@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// failTemplateData 是 -fail-template 的模板数据: PingResult 的全部字段 (如 .Target、
+// .Error、.StatusCode)，加上结果序号、检查名称和错误分类
+type failTemplateData struct {
+	PingResult
+	Seq      int
+	Label    string
+	Category string // classifyError 的分类，如 timeout、refused
+}
+
+var failTemplateFuncs = template.FuncMap{
+	"ms": func(d time.Duration) float64 { return durationMs(d) },
+}
+
+// parseFailTemplate 解析 -fail-template，并用一条示例失败结果试执行，
+// 以便在启动时就发现字段名写错等问题
+func parseFailTemplate(s string) (*template.Template, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := template.New("fail").Funcs(failTemplateFuncs).Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	sample := PingResult{Target: "example.com", Error: errors.New("示例错误"), Timestamp: time.Now()}
+	if err := t.Execute(io.Discard, newFailTemplateData(sample, 1)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func newFailTemplateData(result PingResult, seq int) failTemplateData {
+	return failTemplateData{PingResult: result, Seq: seq, Label: result.label(), Category: classifyError(result.Error)}
+}
+
+// renderFailTemplate 按模板生成失败消息，执行出错时在消息中注明错误而不是丢弃这条结果
+func renderFailTemplate(t *template.Template, result PingResult, seq int) string {
+	var b strings.Builder
+	if err := t.Execute(&b, newFailTemplateData(result, seq)); err != nil {
+		return "-fail-template 执行失败: " + err.Error() + " (" + result.label() + ": " + result.Error.Error() + ")"
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
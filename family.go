@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// RFC 8305 建议的等待时间
+const (
+	resolutionDelay        = 50 * time.Millisecond  // 先收到 A 记录时等待 AAAA 的时间
+	connectionAttemptDelay = 250 * time.Millisecond // 两次连接尝试之间的间隔
+)
+
+// parseFamily 解析 -resolve-family，auto 返回空
+func parseFamily(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "", "auto":
+		return "", nil
+	case "4", "6", "dual":
+		return strings.ToLower(s), nil
+	}
+	return "", fmt.Errorf("无效的 -resolve-family %q (可选 auto, 4, 6, dual)", s)
+}
+
+// ipNetwork 返回按 -resolve-family 解析地址时使用的网络: ip4、ip6 或 ip
+func (o *Options) ipNetwork() string {
+	switch o.Family {
+	case "4", "6":
+		return "ip" + o.Family
+	}
+	return "ip"
+}
+
+// lookupIPAddr 解析主机名，-resolve-family 4 或 6 时只保留该协议族的地址
+func (o *Options) lookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || o.ipNetwork() == "ip" {
+		return addrs, err
+	}
+	var kept []net.IPAddr
+	for _, addr := range addrs {
+		if (addr.IP.To4() != nil) == (o.Family == "4") {
+			kept = append(kept, addr)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("%s 没有 IPv%s 地址", host, o.Family)
+	}
+	return kept, nil
+}
+
+// addrFamily 返回远端地址 (IP 或 IP:端口) 的协议族，不是 IP 时返回空
+func addrFamily(remote string) string {
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	remote, _, _ = strings.Cut(remote, "%")
+	if net.ParseIP(remote) == nil {
+		return ""
+	}
+	return ipFamily(remote)
+}
+
+// familyLabel 返回协议族的显示名称
+func familyLabel(family string) string {
+	if family == "ipv6" {
+		return "IPv6"
+	}
+	return "IPv4"
+}
+
+// dialHappyEyeballs 实现 -resolve-family dual (RFC 8305): 同时查询 AAAA 和 A 记录，
+// 按 IPv6、IPv4 交替排列地址，每隔 250ms (或上一次尝试失败时立即) 发起下一次连接，
+// 使用最先建立的连接并取消其余尝试
+func (o *Options) dialHappyEyeballs(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, o.Timeout)
+	defer cancel()
+	ips, err := resolveBoth(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return raceDial(ctx, network, port, ips)
+}
+
+// resolveBoth 并发查询 AAAA 和 A 记录，返回交替排列的地址，IPv6 在前
+func resolveBoth(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	type answer struct {
+		ips []net.IP
+		err error
+	}
+	lookup := func(network string) <-chan answer {
+		ch := make(chan answer, 1)
+		go func() {
+			ips, err := net.DefaultResolver.LookupIP(ctx, network, host)
+			ch <- answer{ips, err}
+		}()
+		return ch
+	}
+	v6ch, v4ch := lookup("ip6"), lookup("ip4")
+	var v6, v4 answer
+	select {
+	case v6 = <-v6ch:
+		v4 = <-v4ch
+	case v4 = <-v4ch:
+		// A 记录先到时稍等 AAAA，仍未返回就只使用 IPv4 地址
+		select {
+		case v6 = <-v6ch:
+		case <-time.After(resolutionDelay):
+		}
+	}
+	if len(v6.ips) == 0 && len(v4.ips) == 0 {
+		if v4.err != nil {
+			return nil, v4.err
+		}
+		if v6.err != nil {
+			return nil, v6.err
+		}
+		return nil, fmt.Errorf("%s 没有可用的地址", host)
+	}
+
+	var ips []net.IP
+	for i := 0; i < len(v6.ips) || i < len(v4.ips); i++ {
+		if i < len(v6.ips) {
+			ips = append(ips, v6.ips[i])
+		}
+		if i < len(v4.ips) {
+			ips = append(ips, v4.ips[i])
+		}
+	}
+	return ips, nil
+}
+
+// raceDial 依次错开发起到各地址的连接，返回最先成功的连接，之后才建立的连接被关闭。
+// 全部失败时返回第一个错误
+func raceDial(ctx context.Context, network, port string, ips []net.IP) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type attempt struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan attempt, len(ips))
+	dialer := &net.Dialer{}
+	next, pending := 0, 0
+	startNext := func() {
+		address := net.JoinHostPort(ips[next].String(), port)
+		next++
+		pending++
+		go func() {
+			conn, err := dialer.DialContext(ctx, network, address)
+			results <- attempt{conn, err}
+		}()
+	}
+
+	startNext()
+	timer := time.NewTimer(connectionAttemptDelay)
+	defer timer.Stop()
+	var firstErr error
+	for pending > 0 {
+		select {
+		case a := <-results:
+			pending--
+			if a.err == nil {
+				go func(n int) {
+					for ; n > 0; n-- {
+						if late := <-results; late.err == nil {
+							late.conn.Close()
+						}
+					}
+				}(pending)
+				return a.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = a.err
+			}
+			if next < len(ips) {
+				startNext()
+				timer.Reset(connectionAttemptDelay)
+			}
+		case <-timer.C:
+			if next < len(ips) {
+				startNext()
+				timer.Reset(connectionAttemptDelay)
+			}
+		}
+	}
+	return nil, firstErr
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// fifoBuffer 是 -fifo 内部队列能容纳的事件数，消费端跟不上时超出的事件被丢弃
+const fifoBuffer = 1024
+
+// fifoDrainTimeout 是退出时等待队列写完的最长时间，没有消费端时不能一直阻塞退出
+const fifoDrainTimeout = time.Second
+
+// fifoSink 实现 -fifo: 把结果和统计以 NDJSON 写入命名管道。写入在后台 goroutine 中进行，
+// 探测只向有界队列投递事件，队列满时直接丢弃并计数，慢速的消费端不会拖慢探测。
+// 消费端断开后重新打开管道，等待下一个消费端。
+type fifoSink struct {
+	path    string
+	tags    tagFlag
+	events  chan []byte
+	done    chan struct{}
+	closing sync.Once
+	total   atomic.Int64 // 产生的事件总数
+	written atomic.Int64
+	dropped atomic.Int64
+}
+
+func newFIFOSink(path string, tags tagFlag) (*fifoSink, error) {
+	if err := ensureFIFO(path); err != nil {
+		return nil, err
+	}
+	s := &fifoSink{path: path, tags: tags, events: make(chan []byte, fifoBuffer), done: make(chan struct{})}
+	go s.run()
+	return s, nil
+}
+
+// run 顺序写出队列中的事件。打开 FIFO 会阻塞到有消费端为止，写入失败 (消费端退出) 时关闭后重新打开，
+// 失败的那条事件计为丢弃
+func (s *fifoSink) run() {
+	defer close(s.done)
+	var f *os.File
+	for line := range s.events {
+		if f == nil {
+			var err error
+			if f, err = os.OpenFile(s.path, os.O_WRONLY, 0); err != nil {
+				s.dropped.Add(1)
+				continue
+			}
+		}
+		if _, err := f.Write(line); err != nil {
+			f.Close()
+			f = nil
+			s.dropped.Add(1)
+			continue
+		}
+		s.written.Add(1)
+	}
+	if f != nil {
+		f.Close()
+	}
+}
+
+// send 非阻塞地投递一个事件，队列已满时丢弃
+func (s *fifoSink) send(v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.total.Add(1)
+	select {
+	case s.events <- append(line, '\n'):
+	default:
+		s.dropped.Add(1)
+	}
+	return nil
+}
+
+func (s *fifoSink) WriteHeader([]string, string) error { return nil }
+
+func (s *fifoSink) WriteResult(result PingResult, seq int) error {
+	return s.send(newJSONResult(result, seq, s.tags))
+}
+
+func (s *fifoSink) EndRound([]PingResult, int) error { return nil }
+
+func (s *fifoSink) WriteSummary(targets []string, stats []*targetStats) error {
+	for i, t := range targets {
+		if err := s.send(newJSONSummary(t, stats[i], s.tags)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 等待队列写完 (最多 fifoDrainTimeout) 后在标准错误报告写入和丢弃的事件数
+func (s *fifoSink) Close() error {
+	s.closing.Do(func() {
+		close(s.events)
+		select {
+		case <-s.done:
+		case <-time.After(fifoDrainTimeout):
+			// 后台仍阻塞在打开或写入上，没写出的都算丢弃
+			s.dropped.Store(s.total.Load() - s.written.Load())
+		}
+		msg := fmt.Sprintf("FIFO %s: 写入 %d 条事件", s.path, s.written.Load())
+		if dropped := s.dropped.Load(); dropped > 0 {
+			msg = ColorYellow + msg + fmt.Sprintf("，因消费端滞后或未连接丢弃 %d 条", dropped) + ColorReset
+		}
+		fmt.Fprintln(os.Stderr, msg)
+	})
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "errors"
+
+// ensureFIFO 在没有命名管道的平台上不可用
+func ensureFIFO(string) error {
+	return errors.New("当前平台不支持命名管道")
+}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// ensureFIFO 在 path 不存在时创建命名管道，已存在时要求它是命名管道
+func ensureFIFO(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return syscall.Mkfifo(path, 0o600)
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		return fmt.Errorf("%s 已存在且不是命名管道", path)
+	}
+	return nil
+}
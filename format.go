@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// unitStyle 是 -human 和 -si 选择的文本输出单位，JSON 等机器可读输出不受影响
+var unitStyle struct {
+	human bool // 时长按量级选择 ns/µs/ms/s 并保留三位有效数字，字节使用 KiB/MiB
+	si    bool // 字节使用十进制的 KB/MB (1000 进制)，隐含 human
+}
+
+// formatDuration 格式化文本输出中的时长。默认按 precision 取整 (与 Duration.String 相同的写法)，
+// -human 时按量级自动选择单位
+func formatDuration(d, precision time.Duration) string {
+	if !unitStyle.human {
+		return d.Round(precision).String()
+	}
+	abs := d.Abs()
+	switch {
+	case abs == 0:
+		return "0s"
+	case abs < time.Microsecond:
+		return fmt.Sprintf("%dns", d.Nanoseconds())
+	case abs < time.Millisecond:
+		return significant(float64(d)/float64(time.Microsecond)) + "µs"
+	case abs < time.Second:
+		return significant(float64(d)/float64(time.Millisecond)) + "ms"
+	case abs < time.Minute:
+		return significant(d.Seconds()) + "s"
+	}
+	return d.Round(time.Second).String()
+}
+
+// significant 以三位有效数字格式化 [1, 1000) 之间的数
+func significant(v float64) string {
+	decimals := 0
+	switch a := max(v, -v); {
+	case a < 10:
+		decimals = 2
+	case a < 100:
+		decimals = 1
+	}
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+// formatBytes 以 B/KB/MB/GB 显示字节数 (1024 进制)。-human 时使用 KiB/MiB 标明二进制单位，
+// -si 时使用 1000 进制的 KB/MB
+func formatBytes(n int64) string {
+	unit, suffixes := int64(1024), []string{"KB", "MB", "GB", "TB"}
+	switch {
+	case unitStyle.si:
+		unit = 1000
+	case unitStyle.human:
+		suffixes = []string{"KiB", "MiB", "GiB", "TiB"}
+	}
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, suffix := float64(n)/float64(unit), suffixes[0]
+	for _, s := range suffixes[1:] {
+		if value < float64(unit) {
+			break
+		}
+		value /= float64(unit)
+		suffix = s
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
@@ -1,3 +1,16 @@
 module ping-tool
 
 go 1.25.1
+
+require (
+	github.com/quic-go/quic-go v0.61.0
+	golang.org/x/crypto v0.55.0
+	golang.org/x/net v0.58.0
+	golang.org/x/term v0.45.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/kr/text v0.2.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+)
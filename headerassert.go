@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// headerAssertion 是一条 -expect-header 断言:
+//
+//	"Name"          头部必须存在
+//	"Name: value"   值必须完全相等
+//	"Name: ~value"  值必须包含 value
+//	"Name: /re/"    值必须匹配正则表达式 re
+type headerAssertion struct {
+	raw      string
+	name     string
+	value    string
+	present  bool // 只要求头部存在
+	contains bool
+	re       *regexp.Regexp
+}
+
+// headerAssertFlag 实现可重复的 -expect-header 参数
+type headerAssertFlag []headerAssertion
+
+func (f *headerAssertFlag) String() string {
+	parts := make([]string, len(*f))
+	for i, a := range *f {
+		parts[i] = a.raw
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f *headerAssertFlag) Set(s string) error {
+	name, value, hasValue := strings.Cut(s, ":")
+	a := headerAssertion{raw: s, name: strings.TrimSpace(name)}
+	if a.name == "" {
+		return fmt.Errorf("缺少头部名称")
+	}
+
+	value = strings.TrimSpace(value)
+	switch {
+	case !hasValue:
+		a.present = true
+	case len(value) >= 2 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/"):
+		re, err := regexp.Compile(value[1 : len(value)-1])
+		if err != nil {
+			return fmt.Errorf("无效的正则表达式: %v", err)
+		}
+		a.re = re
+	case strings.HasPrefix(value, "~"):
+		a.value, a.contains = value[1:], true
+	default:
+		a.value = value
+	}
+	*f = append(*f, a)
+	return nil
+}
+
+// check 验证响应头，失败时返回描述具体断言的错误
+func (a headerAssertion) check(header http.Header) error {
+	values, ok := header[http.CanonicalHeaderKey(a.name)]
+	if !ok {
+		return fmt.Errorf("响应头断言失败 %q: 缺少 %s", a.raw, a.name)
+	}
+	got := strings.Join(values, ", ")
+
+	switch {
+	case a.present:
+	case a.re != nil:
+		if !a.re.MatchString(got) {
+			return fmt.Errorf("响应头断言失败 %q: 实际值 %q 不匹配", a.raw, got)
+		}
+	case a.contains:
+		if !strings.Contains(got, a.value) {
+			return fmt.Errorf("响应头断言失败 %q: 实际值 %q 不包含 %q", a.raw, got, a.value)
+		}
+	default:
+		if got != a.value {
+			return fmt.Errorf("响应头断言失败 %q: 实际值 %q", a.raw, got)
+		}
+	}
+	return nil
+}
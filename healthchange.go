@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// healthChangeWindow 是 -summary-only-on-change 评估健康状态时使用的最近结果数。
+// 使用滑动窗口而不是累计统计，长时间运行后状态变化仍能及时反映出来。
+const healthChangeWindow = 20
+
+// healthTracker 实现 -summary-only-on-change: 不输出逐条结果，
+// 每轮结束后只在目标的健康状态发生变化时输出一行带时间戳的汇总
+type healthTracker struct {
+	recent map[string][]PingResult
+	last   map[string]healthLevel // 目标上一次的健康状态
+}
+
+func newHealthTracker() *healthTracker {
+	return &healthTracker{recent: make(map[string][]PingResult), last: make(map[string]healthLevel)}
+}
+
+func (h *healthTracker) add(result PingResult) {
+	recent := append(h.recent[result.label()], result)
+	if len(recent) > healthChangeWindow {
+		recent = recent[len(recent)-healthChangeWindow:]
+	}
+	h.recent[result.label()] = recent
+}
+
+// endRound 检查本轮探测过的目标，健康状态变化时输出一行
+func (h *healthTracker) endRound(round []PingResult) {
+	for _, r := range round {
+		recent := h.recent[r.label()]
+		success := 0
+		var total time.Duration
+		for _, res := range recent {
+			if res.Success {
+				success++
+				total += res.ResponseTime
+			}
+		}
+		rate := float64(success) / float64(len(recent)) * 100
+		health := healthOf(rate)
+
+		prev, seen := h.last[r.label()]
+		if seen && prev.key == health.key {
+			continue
+		}
+		h.last[r.label()] = health
+
+		change := health.color + health.label + ColorReset
+		if seen {
+			change = prev.label + " → " + change
+		}
+		avg := "-"
+		if success > 0 {
+			avg = formatDuration(total/time.Duration(success), time.Millisecond)
+		}
+		fmt.Printf("%s %s 健康状态: %s (最近 %d 次成功率 %.1f%%，平均 %s)\n",
+			r.Timestamp.Format("2006-01-02 15:04:05"), r.label(), change, len(recent), rate, avg)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHealthChangeOnlyErrors 确认 -only-errors 不影响 -summary-only-on-change 的成功率窗口:
+// 偶发的失败不应使健康状态变为最差
+func TestHealthChangeOnlyErrors(t *testing.T) {
+	text := &textSink{onlyErrors: true, onChange: newHealthTracker()}
+	out := captureStdout(t, func() {
+		for i := range healthChangeWindow {
+			r := PingResult{Target: "a:80", Success: true, ResponseTime: 10 * time.Millisecond}
+			if i == healthChangeWindow-1 {
+				r = PingResult{Target: "a:80", Error: errors.New("timeout")}
+			}
+			text.WriteResult(r, i+1)
+			text.EndRound([]PingResult{r}, i+1)
+		}
+	})
+	if strings.Contains(out, "成功率 0.0%") || !strings.Contains(out, "最近 20 次成功率 95.0%") {
+		t.Errorf("健康状态应基于全部结果计算 (最近 20 次中 1 次失败):\n%s", out)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// holdKeepAlive 是 -hold 每次写入的数据。HTTP 服务器会忽略请求行之前的空行，
+// 写到 HTTP 端口也不会触发 400 而被服务器主动关闭
+var holdKeepAlive = []byte("\r\n")
+
+// runHold 实现 -hold: 建立一个 TCP 连接并保持 hold 时长，每隔 interval 写入少量数据，
+// 同时持续读取以便立刻发现对端关闭 (FIN) 或重置 (RST)，报告连接存活了多久。
+// 写入间隔大于负载均衡器的空闲超时时，连接会在空闲期间被断开。
+// 返回 true 表示连接在 hold 时长之前被断开。
+func runHold(target string, opts *Options, hold, interval time.Duration) (bool, error) {
+	target = withDefaultPort(target, opts.defaultPort())
+	start := time.Now()
+	conn, err := opts.dial("tcp", target)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	fmt.Printf("已连接 %s (%s)，耗时 %v，保持 %v，每 %v 写入 %d 字节\n\n",
+		target, conn.RemoteAddr(), formatDuration(time.Since(start), time.Millisecond), hold, interval, len(holdKeepAlive))
+	start = time.Now()
+
+	// 服务器的回复直接丢弃，读取只用于发现连接断开
+	closed := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, conn)
+		if err == nil {
+			err = errors.New("对端关闭了连接 (FIN)")
+		}
+		closed <- explainReset(err)
+	}()
+
+	stop := stopOnSignal()
+	deadline := time.NewTimer(hold)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	writes := 0
+	lastWrite := start // 断开时距上次写入的时间接近空闲超时
+	var dropErr error
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(opts.Timeout))
+			if _, err := conn.Write(holdKeepAlive); err != nil {
+				dropErr = fmt.Errorf("写入失败: %w", explainReset(err))
+				break loop
+			}
+			writes++
+			lastWrite = time.Now()
+			fmt.Printf("[%d] %s已保持 %v: 写入成功%s\n", writes, ColorGreen, formatDuration(time.Since(start), time.Second), ColorReset)
+		case err := <-closed:
+			dropErr = err
+			break loop
+		case <-deadline.C:
+			break loop
+		case <-stop:
+			break loop
+		}
+	}
+	alive := time.Since(start).Round(time.Millisecond)
+	idle := time.Since(lastWrite).Round(time.Millisecond)
+
+	fmt.Printf("\n%s=== 连接保持结果 ===%s\n", ColorCyan, ColorReset)
+	fmt.Printf("写入次数: %d\n", writes)
+	switch {
+	case dropErr != nil:
+		fmt.Printf("%s连接在 %v 后断开: %v%s\n", ColorRed, formatDuration(alive, time.Millisecond), dropErr, ColorReset)
+		fmt.Printf("断开时距上次写入 %v (由空闲超时断开时接近该超时)\n", formatDuration(idle, time.Millisecond))
+	case alive >= hold:
+		fmt.Printf("%s连接保持 %v 仍然存活%s\n", ColorGreen, formatDuration(alive, time.Millisecond), ColorReset)
+	default:
+		fmt.Printf("已停止，连接保持 %v 仍然存活\n", formatDuration(alive, time.Millisecond))
+	}
+	return dropErr != nil, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// hookSink 实现 -on-failure 和 -on-recovery: 探测失败时、以及检查从失败恢复为成功时执行 shell 命令。
+// 命令在后台执行，不阻塞探测循环；同一检查的同一钩子上一次还没结束时跳过本次，
+// 避免持续故障期间堆积多个重启脚本。命令的输出写到 stderr，结束后报告退出状态。
+type hookSink struct {
+	onFailure  string
+	onRecovery string
+	timeout    time.Duration
+
+	failures  map[string]int       // 检查名称 -> 连续失败次数
+	downSince map[string]time.Time // 检查名称 -> 本次连续失败的第一次失败时间
+
+	mu      sync.Mutex
+	running map[string]bool // 检查名称和事件 -> 钩子正在执行
+	wg      sync.WaitGroup
+}
+
+func newHookSink(onFailure, onRecovery string, timeout time.Duration) *hookSink {
+	return &hookSink{
+		onFailure:  onFailure,
+		onRecovery: onRecovery,
+		timeout:    timeout,
+		failures:   make(map[string]int),
+		downSince:  make(map[string]time.Time),
+		running:    make(map[string]bool),
+	}
+}
+
+func (s *hookSink) WriteHeader([]string, string) error { return nil }
+
+func (s *hookSink) WriteResult(result PingResult, seq int) error {
+	label := result.label()
+	if !result.Success {
+		if s.failures[label] == 0 {
+			s.downSince[label] = result.Timestamp
+		}
+		s.failures[label]++
+		if s.onFailure != "" {
+			s.run("failure", s.onFailure, result, seq, hookEnv(result, seq, "failure", s.failures[label], 0))
+		}
+		return nil
+	}
+	if n := s.failures[label]; n > 0 {
+		down := result.Timestamp.Sub(s.downSince[label])
+		delete(s.failures, label)
+		delete(s.downSince, label)
+		if s.onRecovery != "" {
+			s.run("recovery", s.onRecovery, result, seq, hookEnv(result, seq, "recovery", n, down))
+		}
+	}
+	return nil
+}
+
+// hookEnv 返回传给钩子命令的环境变量
+func hookEnv(result PingResult, seq int, event string, failures int, down time.Duration) []string {
+	env := []string{
+		"PING_EVENT=" + event,
+		"PING_TARGET=" + result.Target,
+		"PING_NAME=" + result.label(),
+		"PING_SEQ=" + strconv.Itoa(seq),
+		"PING_TIME=" + result.Timestamp.Format(time.RFC3339),
+		"PING_CONSECUTIVE_FAILURES=" + strconv.Itoa(failures),
+	}
+	if result.StatusCode != 0 {
+		env = append(env, "PING_STATUS_CODE="+strconv.Itoa(result.StatusCode))
+	}
+	if result.Error != nil {
+		env = append(env, "PING_ERROR="+result.Error.Error(), "PING_ERROR_CATEGORY="+classifyError(result.Error))
+	}
+	if event == "recovery" {
+		env = append(env,
+			"PING_RESPONSE_TIME_MS="+strconv.FormatFloat(durationMs(result.ResponseTime), 'f', 3, 64),
+			"PING_DOWNTIME_SECONDS="+strconv.FormatFloat(down.Seconds(), 'f', 3, 64))
+	}
+	return env
+}
+
+// run 在后台执行钩子命令，同一检查的同一事件已有命令在执行时跳过
+func (s *hookSink) run(event, command string, result PingResult, seq int, env []string) {
+	key := result.label() + "\x00" + event
+	s.mu.Lock()
+	if s.running[key] {
+		s.mu.Unlock()
+		fmt.Fprintf(os.Stderr, ColorYellow+"[%d] -on-%s: %s 的上一次命令仍在执行，跳过\n"+ColorReset, seq, event, result.label())
+		return
+	}
+	s.running[key] = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, key)
+			s.mu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		defer cancel()
+		cmd := hookCommand(ctx, command)
+		cmd.Env = append(os.Environ(), env...)
+		cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+		start := time.Now()
+		err := cmd.Run()
+		elapsed := formatDuration(time.Since(start), time.Millisecond)
+
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+			fmt.Fprintf(os.Stderr, ColorYellow+"[%d] -on-%s: %s 的命令执行成功 (耗时 %s)\n"+ColorReset, seq, event, result.label(), elapsed)
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			fmt.Fprintf(os.Stderr, ColorRed+"[%d] -on-%s: %s 的命令超过 %v 未结束，已终止\n"+ColorReset, seq, event, result.label(), s.timeout)
+		case errors.As(err, &exitErr):
+			fmt.Fprintf(os.Stderr, ColorRed+"[%d] -on-%s: %s 的命令退出码 %d (耗时 %s)\n"+ColorReset, seq, event, result.label(), exitErr.ExitCode(), elapsed)
+		default:
+			fmt.Fprintf(os.Stderr, ColorRed+"[%d] -on-%s: %s 的命令无法执行: %v\n"+ColorReset, seq, event, result.label(), err)
+		}
+	}()
+}
+
+func (s *hookSink) EndRound([]PingResult, int) error            { return nil }
+func (s *hookSink) WriteSummary([]string, []*targetStats) error { return nil }
+
+// Close 等待仍在执行的钩子结束，恢复通知等命令不会因为程序退出而被中断
+func (s *hookSink) Close() error {
+	s.wg.Wait()
+	return nil
+}
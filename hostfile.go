@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// loadHostsFile 解析标准 hosts 文件格式 (IP 后跟一个或多个主机名，# 开始注释)。
+// 同一主机名出现多次时以第一次为准，与系统解析器的行为一致。
+func loadHostsFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hosts := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: 缺少主机名", path, lineNo)
+		}
+
+		ip := fields[0]
+		if zoneless, _, _ := strings.Cut(ip, "%"); net.ParseIP(zoneless) == nil {
+			return nil, fmt.Errorf("%s:%d: 无效的 IP 地址 %q", path, lineNo, ip)
+		}
+		for _, name := range fields[1:] {
+			name = strings.ToLower(strings.TrimSuffix(name, "."))
+			if _, exists := hosts[name]; !exists {
+				hosts[name] = ip
+			}
+		}
+	}
+	return hosts, scanner.Err()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// acceptEncoding 是探测请求声明支持的压缩格式。Transport 的自动解压已关闭，由 decodeBody
+// 自行解码，这样才能同时得到线上传输的字节数和解码后的内容，并支持 deflate
+const acceptEncoding = "gzip, deflate"
+
+// maxKeepAliveDrain 是 -keepalive 模式下为复用连接最多丢弃读取的响应体字节数
+const maxKeepAliveDrain = 1 << 20
+
+// readFullBody 读完整个响应体以确认传输完整，只保留前 maxCheckBody 字节供断言使用
+func readFullBody(r io.Reader) ([]byte, int64, error) {
+	var head bytes.Buffer
+	n, err := io.Copy(&head, io.LimitReader(r, maxCheckBody))
+	if err != nil {
+		return head.Bytes(), n, err
+	}
+	rest, err := io.Copy(io.Discard, r)
+	return head.Bytes(), n + rest, err
+}
+
+// countingReader 统计从底层读取的字节数
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// decodeBody 按 Content-Encoding 包装响应体，返回解码后的 reader 和统计解码前字节数的计数器
+func decodeBody(resp *http.Response) (io.Reader, *countingReader, error) {
+	wire := &countingReader{r: resp.Body}
+	enc := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	var (
+		r   io.Reader
+		err error
+	)
+	switch enc {
+	case "", "identity":
+		return wire, wire, nil
+	case "gzip", "x-gzip":
+		r, err = gzip.NewReader(wire)
+	case "deflate":
+		// 规范要求 zlib 封装，但不少服务器直接发送原始 deflate 数据
+		br := bufio.NewReader(wire)
+		if header, peekErr := br.Peek(2); peekErr == nil && isZlibHeader(header) {
+			r, err = zlib.NewReader(br)
+		} else {
+			r = flate.NewReader(br)
+		}
+	default:
+		return nil, wire, fmt.Errorf("不支持的内容编码 %q", enc)
+	}
+	if errors.Is(err, io.EOF) {
+		return wire, wire, nil // 空响应体 (如 304、HEAD) 也可能带有 Content-Encoding
+	}
+	if err != nil {
+		return nil, wire, fmt.Errorf("解码 %s 响应体失败: %w", enc, err)
+	}
+	return r, wire, nil
+}
+
+// isZlibHeader 判断前两个字节是否为 zlib 头 (RFC 1950): CM=8 且 CMF*256+FLG 是 31 的倍数
+func isZlibHeader(b []byte) bool {
+	return b[0]&0x0f == 8 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
+
+var (
+	sharedTransportsMu sync.Mutex
+	sharedTransports   = make(map[*Options]*http.Transport) // -all-ips 时每个地址的检查各有一份选项和连接池
+)
+
+// newHTTPTransport 创建经过 dialContext 拨号并统计字节数的 Transport
+func newHTTPTransport(opts *Options) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableCompression = true
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := opts.dialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &countingConn{Conn: conn}, nil
+	}
+	if !opts.TLSVerify.full() {
+		transport.DialTLSContext = opts.TLSVerify.dialTLS(opts)
+	} else if opts.TLSVerify.roots != nil {
+		transport.TLSClientConfig = &tls.Config{RootCAs: opts.TLSVerify.roots}
+	}
+	return transport
+}
+
+// httpTransport 返回本次请求使用的 Transport 以及请求结束后的清理函数。
+// 启用 -keepalive 时所有请求共享同一个 Transport 以复用连接，否则每次新建并在结束后关闭连接。
+func (o *Options) httpTransport() (*http.Transport, func()) {
+	if o.KeepAlive {
+		sharedTransportsMu.Lock()
+		defer sharedTransportsMu.Unlock()
+		if sharedTransports[o] == nil {
+			sharedTransports[o] = newHTTPTransport(o)
+		}
+		return sharedTransports[o], func() {}
+	}
+	transport := newHTTPTransport(o)
+	return transport, transport.CloseIdleConnections
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// defaultICMPSize 与系统 ping 默认的 56 字节数据长度一致
+const defaultICMPSize = 56
+
+// errPayloadCorrupted 表示收到了回复但回显数据与发送内容不一致，与丢包区分统计
+var errPayloadCorrupted = errors.New("回显数据与发送内容不一致")
+
+var icmpSeq atomic.Uint32
+
+// parsePattern 解析 -pattern 的十六进制填充内容
+func parsePattern(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	pattern, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("无效的十六进制内容 %q", s)
+	}
+	if len(pattern) == 0 {
+		return nil, fmt.Errorf("填充内容不能为空")
+	}
+	return pattern, nil
+}
+
+// icmpPayload 按 -size 和 -pattern 构造回显数据，未指定 pattern 时使用递增字节
+func icmpPayload(size int, pattern []byte) []byte {
+	payload := make([]byte, size)
+	for i := range payload {
+		if len(pattern) > 0 {
+			payload[i] = pattern[i%len(pattern)]
+		} else {
+			payload[i] = byte(i)
+		}
+	}
+	return payload
+}
+
+// listenICMP 优先使用原始套接字，没有权限时改用非特权 ICMP 套接字 (Linux 需要
+// net.ipv4.ping_group_range 允许当前用户)。privileged 为 false 时内核会改写 ID。
+func listenICMP(v6 bool) (conn *icmp.PacketConn, privileged bool, err error) {
+	network, udpNetwork, addr := "ip4:icmp", "udp4", "0.0.0.0"
+	if v6 {
+		network, udpNetwork, addr = "ip6:ipv6-icmp", "udp6", "::"
+	}
+	if conn, err = icmp.ListenPacket(network, addr); err == nil {
+		return conn, true, nil
+	}
+	conn, err = icmp.ListenPacket(udpNetwork, addr)
+	return conn, false, err
+}
+
+// pingICMP 发送一个 ICMP Echo 请求并等待匹配的回复，校验回显数据是否完整
+func pingICMP(target string, opts *Options) PingResult {
+	result := PingResult{Target: target}
+
+	// ICMP 没有端口，[IPv6] 写法只需去掉方括号
+	host := strings.TrimSuffix(strings.TrimPrefix(target, "["), "]")
+	if addr, ok := opts.overrideAddr(target, ""); ok {
+		host = addr
+	}
+	ipAddr, err := net.ResolveIPAddr(opts.ipNetwork(), host)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	v6 := ipAddr.IP.To4() == nil
+	result.RemoteAddr = ipAddr.String()
+
+	conn, privileged, err := listenICMP(v6)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, ColorYellow+"注意: ICMP ping 需要 root 权限，改用 TCP 连接测试"+ColorReset)
+		return pingTCP(target, opts)
+	}
+	defer conn.Close()
+
+	var echoType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	proto := 1 // ICMPv4
+	if v6 {
+		echoType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+		proto = 58 // ICMPv6
+	}
+
+	id := os.Getpid() & 0xffff
+	seq := int(icmpSeq.Add(1) & 0xffff)
+	payload := icmpPayload(opts.ICMPSize, opts.ICMPPattern)
+	msg := icmp.Message{
+		Type: echoType,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: payload},
+	}
+	packet, err := msg.Marshal(nil)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	var dst net.Addr = ipAddr
+	if !privileged {
+		dst = &net.UDPAddr{IP: ipAddr.IP, Zone: ipAddr.Zone}
+	}
+
+	deadline := time.Now().Add(opts.Timeout)
+	if err := conn.SetDeadline(deadline); err != nil {
+		result.Error = err
+		return result
+	}
+
+	start := time.Now()
+	n, err := conn.WriteTo(packet, dst)
+	result.BytesSent = int64(n)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			result.ResponseTime = time.Since(start)
+			result.Error = err
+			return result
+		}
+
+		reply, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || reply.Type != replyType {
+			continue
+		}
+		echo, ok := reply.Body.(*icmp.Echo)
+		// 非特权套接字的 ID 由内核分配，只能依靠序列号匹配
+		if !ok || echo.Seq != seq || (privileged && echo.ID != id) {
+			continue
+		}
+
+		result.ResponseTime = time.Since(start)
+		result.BytesRecv = int64(n)
+		if !bytes.Equal(echo.Data, payload) {
+			result.Corrupted = true
+			result.Error = errPayloadCorrupted
+			return result
+		}
+		result.Success = true
+		return result
+	}
+}
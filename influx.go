@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// influxEscaper 转义 line protocol 中标签键、标签值里的逗号、等号和空格
+var influxEscaper = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+
+// influxStringEscaper 转义字符串字段值中的引号和反斜杠
+var influxStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// influxTimeout 是向 InfluxDB 提交一批数据的超时
+const influxTimeout = 10 * time.Second
+
+// influxAuth 是 InfluxDB 2.x 写入接口的组织、bucket 和令牌
+type influxAuth struct {
+	org, bucket, token string
+}
+
+// influxSink 实现 -influx: 每次探测输出一行 InfluxDB line protocol，如
+// ping,target=example.com,type=http success=1,rtt=0.012 1700000000000000000。
+// dest 是文件时逐行写入；是 http(s) URL 时每轮结束后把本轮的行 POST 到写入接口。
+type influxSink struct {
+	f        *os.File // 写入文件时使用
+	endpoint string   // 写入 HTTP 接口时使用
+	token    string
+	client   *http.Client
+	tags     tagFlag
+	pingType string
+	pending  bytes.Buffer
+}
+
+func newInfluxSink(dest string, tags tagFlag, auth influxAuth) (*influxSink, error) {
+	s := &influxSink{tags: tags}
+	if !strings.HasPrefix(dest, "http://") && !strings.HasPrefix(dest, "https://") {
+		f, err := createOutputFile(dest)
+		if err != nil {
+			return nil, err
+		}
+		s.f = f
+		return s, nil
+	}
+
+	u, err := url.Parse(dest)
+	if err != nil {
+		return nil, err
+	}
+	// 只给出服务器地址时使用 2.x 的写入接口
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/api/v2/write"
+	}
+	q := u.Query()
+	if auth.org != "" {
+		q.Set("org", auth.org)
+	}
+	if auth.bucket != "" {
+		q.Set("bucket", auth.bucket)
+	}
+	q.Set("precision", "ns")
+	u.RawQuery = q.Encode()
+	s.endpoint = u.String()
+	s.token = auth.token
+	if s.token == "" {
+		s.token = os.Getenv("INFLUX_TOKEN")
+	}
+	s.client = &http.Client{Timeout: influxTimeout}
+	return s, nil
+}
+
+func init() {
+	registerSink("influx", sinkFormat{
+		help:       "InfluxDB line protocol，dest 为文件或写入接口 URL",
+		allResults: true,
+		create: func(dest string, tags tagFlag) (OutputSink, error) {
+			return newInfluxSink(dest, tags, influxAuth{})
+		},
+	})
+}
+
+func (s *influxSink) WriteHeader(_ []string, pingType string) error {
+	s.pingType = strings.ToLower(pingType)
+	return nil
+}
+
+// line 格式化一次探测: 检查和 -tag 作为标签，结果作为字段，时间戳为纳秒
+func (s *influxSink) line(result PingResult) string {
+	var b strings.Builder
+	b.WriteString("ping")
+	tag := func(key, value string) {
+		if value != "" { // line protocol 不允许空的标签值
+			b.WriteString("," + influxEscaper.Replace(key) + "=" + influxEscaper.Replace(value))
+		}
+	}
+	tag("target", result.Target)
+	tag("type", s.pingType)
+	tag("name", result.Name)
+	for _, t := range s.tags {
+		tag(t.key, t.value)
+	}
+
+	fields := []string{"success=0"}
+	if result.Success {
+		fields = []string{"success=1", "rtt=" + strconv.FormatFloat(result.ResponseTime.Seconds(), 'f', -1, 64)}
+	}
+	if result.StatusCode != 0 {
+		fields = append(fields, "status_code="+strconv.Itoa(result.StatusCode)+"i")
+	}
+	if result.Error != nil {
+		fields = append(fields, `error="`+influxStringEscaper.Replace(result.Error.Error())+`"`)
+	}
+	fmt.Fprintf(&b, " %s %d\n", strings.Join(fields, ","), result.Timestamp.UnixNano())
+	return b.String()
+}
+
+func (s *influxSink) WriteResult(result PingResult, seq int) error {
+	if s.f != nil {
+		_, err := s.f.WriteString(s.line(result))
+		return err
+	}
+	s.pending.WriteString(s.line(result))
+	return nil
+}
+
+func (s *influxSink) EndRound([]PingResult, int) error { return s.flush() }
+
+func (s *influxSink) WriteSummary([]string, []*targetStats) error { return nil }
+
+// flush 把积累的行提交到写入接口，写入文件时不需要
+func (s *influxSink) flush() error {
+	if s.f != nil || s.pending.Len() == 0 {
+		return nil
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(s.pending.Bytes()))
+	if err != nil {
+		return err
+	}
+	s.pending.Reset()
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Token "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("InfluxDB: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("InfluxDB 写入失败: %s %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func (s *influxSink) Close() error {
+	if s.f != nil {
+		return closeOutputFile(s.f)
+	}
+	return s.flush()
+}
@@ -0,0 +1,35 @@
+package main
+
+import "time"
+
+// adaptiveInterval 实现 -interval-adaptive: 目标状态变化时把间隔缩短到下限以密集采样，
+// 状态稳定时每轮加倍直到上限。
+type adaptiveInterval struct {
+	current time.Duration
+	min     time.Duration
+	max     time.Duration
+	last    map[string]bool // 每个检查 (按 label 区分) 上一次的成功状态
+}
+
+func newAdaptiveInterval(base, min, max time.Duration) *adaptiveInterval {
+	return &adaptiveInterval{current: base, min: min, max: max, last: make(map[string]bool)}
+}
+
+// next 根据本轮结果计算下一次间隔
+func (a *adaptiveInterval) next(round []PingResult) time.Duration {
+	changed := false
+	for _, r := range round {
+		if prev, ok := a.last[r.label()]; ok && prev != r.Success {
+			changed = true
+		}
+		a.last[r.label()] = r.Success
+	}
+
+	if changed {
+		a.current = a.min
+	} else {
+		a.current *= 2
+	}
+	a.current = max(a.min, min(a.current, a.max))
+	return a.current
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAdaptiveIntervalPerCheck 确认同一目标的多个检查 (如 -all-ips 展开的各地址) 分别记录状态，
+// 一个地址稳定失败、另一个稳定成功时不会被误判为状态变化
+func TestAdaptiveIntervalPerCheck(t *testing.T) {
+	a := newAdaptiveInterval(time.Second, time.Second, 8*time.Second)
+	round := []PingResult{
+		{Target: "example.com:80", Name: "example.com:80 [192.0.2.1]", Success: true},
+		{Target: "example.com:80", Name: "example.com:80 [192.0.2.2]", Success: false},
+	}
+	want := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second}
+	for i, w := range want {
+		if got := a.next(round); got != w {
+			t.Fatalf("第 %d 轮间隔 = %v, 期望 %v", i+1, got, w)
+		}
+	}
+	round[0].Success = false
+	if got := a.next(round); got != time.Second {
+		t.Errorf("状态变化后间隔 = %v, 期望回到下限 1s", got)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// jsonSchemaVersion 是 -o json 输出结构的版本号。
+// 字段名和含义属于对外契约：只允许新增字段，重命名、删除或修改含义时必须递增此版本。
+const jsonSchemaVersion = 1
+
+// jsonResult 是 -o json 中每次 ping 的记录 (type=result)
+type jsonResult struct {
+	SchemaVersion  int               `json:"schema_version"`
+	Type           string            `json:"type"`
+	Timestamp      time.Time         `json:"timestamp"`
+	Seq            int               `json:"seq"`
+	Target         string            `json:"target"`
+	Name           string            `json:"name"`
+	Success        bool              `json:"success"`
+	ResponseTimeMs float64           `json:"response_time_ms"`
+	StatusCode     int               `json:"status_code"`
+	Error          string            `json:"error"`
+	ErrorCategory  string            `json:"error_category"`
+	Retries        int               `json:"retries"`
+	ServerTimeMs   float64           `json:"server_time_ms"`
+	ConnectTimeMs  float64           `json:"connect_time_ms"`
+	TTFBMs         float64           `json:"ttfb_ms"`
+	TotalTimeMs    float64           `json:"total_time_ms"`
+	ClockSkewMs    float64           `json:"clock_skew_ms"`
+	TraceID        string            `json:"trace_id"`
+	Anomaly        bool              `json:"anomaly"`
+	RemoteAddr     string            `json:"remote_addr"`
+	Proxy          string            `json:"proxy,omitempty"`
+	AddressFamily  string            `json:"address_family,omitempty"`
+	Corrupted      bool              `json:"corrupted"`
+	BytesSent      int64             `json:"bytes_sent"`
+	BytesReceived  int64             `json:"bytes_received"`
+	ConnReused     bool              `json:"conn_reused"`
+	Encoding       string            `json:"content_encoding"`
+	BodyBytes      int64             `json:"body_bytes"`
+	WireBytes      int64             `json:"body_wire_bytes"`
+	Stages         []jsonStage       `json:"stages,omitempty"`
+	TLSChain       []jsonCert        `json:"tls_chain,omitempty"`
+	TLSChainIssues []string          `json:"tls_chain_issues,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+}
+
+// jsonStage 是 -probe-order 中一个阶段的结果
+type jsonStage struct {
+	Name       string  `json:"name"`
+	DurationMs float64 `json:"duration_ms"`
+	Success    bool    `json:"success"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// jsonCert 是 -dump-chain 记录的一张证书
+type jsonCert struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	SANs      []string  `json:"sans,omitempty"`
+}
+
+// jsonSummary 是 -o json 中每个目标的统计记录 (type=summary)
+type jsonSummary struct {
+	SchemaVersion int               `json:"schema_version"`
+	Type          string            `json:"type"`
+	Target        string            `json:"target"`
+	Name          string            `json:"name"`
+	AddressFamily string            `json:"address_family,omitempty"` // -all-ips 展开的检查: ipv4 或 ipv6
+	Sent          int               `json:"sent"`
+	Success       int               `json:"success"`
+	Failed        int               `json:"failed"`
+	Timeouts      int               `json:"timeouts"`
+	LossPercent   float64           `json:"loss_percent"`
+	AvgMs         float64           `json:"avg_ms"`
+	MinMs         float64           `json:"min_ms"`
+	MaxMs         float64           `json:"max_ms"`
+	StdDevMs      float64           `json:"stddev_ms"`
+	P50Ms         float64           `json:"p50_ms"`
+	P90Ms         float64           `json:"p90_ms"`
+	P99Ms         float64           `json:"p99_ms"`
+	Retries       int               `json:"retries"`
+	Corrupted     int               `json:"corrupted"`
+	CorruptedPct  float64           `json:"corrupted_percent"`
+	BytesSent     int64             `json:"bytes_sent"`
+	BytesReceived int64             `json:"bytes_received"`
+	ConnReused    int               `json:"conn_reused"`
+	Anomalies     int               `json:"anomalies"`
+	DNSFailures   map[string]int    `json:"dns_failures,omitempty"`
+	Health        string            `json:"health"`
+	Tags          map[string]string `json:"tags,omitempty"`
+}
+
+// durationMs 将时长转换为毫秒浮点数
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func newJSONResult(result PingResult, seq int, tags tagFlag) jsonResult {
+	rec := jsonResult{
+		SchemaVersion:  jsonSchemaVersion,
+		Type:           "result",
+		Timestamp:      result.Timestamp,
+		Seq:            seq,
+		Target:         result.Target,
+		Name:           result.Name,
+		Success:        result.Success,
+		ResponseTimeMs: durationMs(result.ResponseTime),
+		StatusCode:     result.StatusCode,
+		Retries:        result.Retries,
+		ServerTimeMs:   durationMs(result.ServerTime),
+		ConnectTimeMs:  durationMs(result.ConnectTime),
+		TTFBMs:         durationMs(result.TTFB),
+		TotalTimeMs:    durationMs(result.TotalTime),
+		ClockSkewMs:    durationMs(result.ClockSkew),
+		TraceID:        result.TraceID,
+		Anomaly:        result.Anomaly,
+		RemoteAddr:     result.RemoteAddr,
+		Proxy:          result.Proxy,
+		AddressFamily:  result.Family,
+		Corrupted:      result.Corrupted,
+		BytesSent:      result.BytesSent,
+		BytesReceived:  result.BytesRecv,
+		ConnReused:     result.ConnReused,
+		Encoding:       result.Encoding,
+		BodyBytes:      result.BodyBytes,
+		WireBytes:      result.WireBytes,
+		Tags:           tags.jsonTags(),
+	}
+	if result.Error != nil {
+		rec.Error = result.Error.Error()
+		rec.ErrorCategory = classifyError(result.Error)
+	}
+	for _, st := range result.Stages {
+		rec.Stages = append(rec.Stages, jsonStage{Name: st.Name, DurationMs: durationMs(st.Duration), Success: st.Error == "", Error: st.Error})
+	}
+	if result.Chain != nil {
+		for _, c := range result.Chain.Certs {
+			rec.TLSChain = append(rec.TLSChain, jsonCert{Subject: c.Subject, Issuer: c.Issuer, NotBefore: c.NotBefore, NotAfter: c.NotAfter, SANs: c.SANs})
+		}
+		rec.TLSChainIssues = result.Chain.Issues
+	}
+	return rec
+}
+
+func newJSONSummary(target string, stats *targetStats, tags tagFlag) jsonSummary {
+	sum := stats.summary()
+	return jsonSummary{
+		SchemaVersion: jsonSchemaVersion,
+		Type:          "summary",
+		Target:        target,
+		Name:          stats.name,
+		AddressFamily: stats.family,
+		Sent:          sum.Sent,
+		Success:       sum.Success,
+		Failed:        sum.Failed,
+		Timeouts:      sum.Timeouts,
+		LossPercent:   sum.LossPercent,
+		AvgMs:         durationMs(sum.Avg),
+		MinMs:         durationMs(sum.Min),
+		MaxMs:         durationMs(sum.Max),
+		StdDevMs:      durationMs(sum.StdDev),
+		P50Ms:         durationMs(sum.P50),
+		P90Ms:         durationMs(sum.P90),
+		P99Ms:         durationMs(sum.P99),
+		Retries:       sum.Retries,
+		Corrupted:     sum.Corrupted,
+		CorruptedPct:  sum.CorruptRate,
+		BytesSent:     sum.BytesSent,
+		BytesReceived: sum.BytesRecv,
+		ConnReused:    sum.ConnReused,
+		Anomalies:     sum.Anomalies,
+		DNSFailures:   sum.DNSFailures,
+		Health:        healthOf(sum.SuccessRate()).key,
+		Tags:          tags.jsonTags(),
+	}
+}
+
+// jsonSink 以 NDJSON 形式 (每行一个对象) 输出结果和统计
+type jsonSink struct {
+	f    *os.File
+	enc  *json.Encoder
+	tags tagFlag
+}
+
+func init() {
+	registerSink("json", sinkFormat{
+		help:   "每行一个 JSON 对象",
+		create: fileSink(func(f *os.File, tags tagFlag) OutputSink { return newJSONSink(f, tags) }),
+	})
+}
+
+func newJSONSink(f *os.File, tags tagFlag) *jsonSink {
+	return &jsonSink{f: f, enc: json.NewEncoder(f), tags: tags}
+}
+
+func (j *jsonSink) WriteHeader([]string, string) error { return nil }
+
+func (j *jsonSink) WriteResult(result PingResult, seq int) error {
+	return j.enc.Encode(newJSONResult(result, seq, j.tags))
+}
+
+func (j *jsonSink) EndRound([]PingResult, int) error { return nil }
+
+func (j *jsonSink) WriteSummary(targets []string, stats []*targetStats) error {
+	for i, t := range targets {
+		if err := j.enc.Encode(newJSONSummary(t, stats[i], j.tags)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *jsonSink) Close() error { return closeOutputFile(j.f) }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "用当前输出重写 testdata 中的 golden 文件")
+
+// TestJSONGolden 锁定 -o json 的序列化结果: 字段名、顺序或格式的任何变化都会使测试失败。
+// 有意修改输出结构时按 jsonSchemaVersion 的约定处理，然后用 go test -run TestJSONGolden -update 更新 golden 文件
+func TestJSONGolden(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 600_000_000, time.UTC)
+	results := []PingResult{
+		{
+			Target:       "https://example.com/health",
+			Name:         "api",
+			Timestamp:    ts,
+			Success:      true,
+			ResponseTime: 42500 * time.Microsecond,
+			StatusCode:   200,
+			ServerTime:   12 * time.Millisecond,
+			TTFB:         40 * time.Millisecond,
+			RemoteAddr:   "93.184.216.34:443",
+			BytesSent:    512,
+			BytesRecv:    2048,
+			ConnReused:   true,
+			Encoding:     "gzip",
+			Stages: []stageResult{
+				{Name: "dns", Duration: 2 * time.Millisecond},
+				{Name: "http", Duration: 40500 * time.Microsecond},
+			},
+		},
+		{
+			Target:       "https://example.com/health",
+			Name:         "api",
+			Timestamp:    ts.Add(time.Second),
+			ResponseTime: 5 * time.Second,
+			Error:        fmt.Errorf("请求超时: %w", os.ErrDeadlineExceeded),
+			Retries:      1,
+		},
+		{
+			Target:       "https://example.com/health",
+			Name:         "api",
+			Timestamp:    ts.Add(2 * time.Second),
+			ResponseTime: 30 * time.Millisecond,
+			Error:        fmt.Errorf("dial tcp 93.184.216.34:443: %w", syscall.ECONNREFUSED),
+		},
+	}
+
+	var tags tagFlag
+	if err := tags.Set("env=prod"); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.CreateTemp(t.TempDir(), "out-*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := newJSONSink(f, tags)
+	stats := newTargetStats(0, true)
+	stats.name = "api"
+	for i, r := range results {
+		stats.add(r)
+		if err := sink.WriteResult(r, i+1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.WriteSummary([]string{results[0].Target}, []*targetStats{stats}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "json_output.golden")
+	if *updateGolden {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("JSON 输出与 %s 不一致 (有意修改时用 -update 更新)\n得到:\n%s\n期望:\n%s", golden, got, want)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonAssertion 是一条 -expect-json 断言，如 `.status=pass` 或 `.checks[0].healthy=true`
+type jsonAssertion struct {
+	raw  string
+	path []jsonPathStep
+	want string
+}
+
+// jsonPathStep 是路径中的一段: 对象字段或数组下标
+type jsonPathStep struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+// jsonAssertFlag 实现可重复的 -expect-json 参数
+type jsonAssertFlag []jsonAssertion
+
+func (f *jsonAssertFlag) String() string {
+	parts := make([]string, len(*f))
+	for i, a := range *f {
+		parts[i] = a.raw
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f *jsonAssertFlag) Set(value string) error {
+	a, err := parseJSONAssertion(value)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, a)
+	return nil
+}
+
+// parseJSONAssertion 解析 路径=期望值，期望值两侧的双引号可省略
+func parseJSONAssertion(s string) (jsonAssertion, error) {
+	pathStr, want, ok := strings.Cut(s, "=")
+	if !ok {
+		return jsonAssertion{}, fmt.Errorf("格式应为 .路径=期望值")
+	}
+	path, err := parseJSONPath(strings.TrimSpace(pathStr))
+	if err != nil {
+		return jsonAssertion{}, err
+	}
+	want = strings.TrimSpace(want)
+	if unquoted, err := strconv.Unquote(want); err == nil {
+		want = unquoted
+	}
+	return jsonAssertion{raw: s, path: path, want: want}, nil
+}
+
+// parseJSONPath 解析 .a.b[0].c 形式的路径，"." 表示根节点
+func parseJSONPath(s string) ([]jsonPathStep, error) {
+	if !strings.HasPrefix(s, ".") {
+		return nil, fmt.Errorf("路径必须以 . 开头: %q", s)
+	}
+	var steps []jsonPathStep
+	rest := s[1:]
+	for rest != "" {
+		switch {
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("路径中的 [ 未闭合: %q", s)
+			}
+			idx, err := strconv.Atoi(rest[1:end])
+			if err != nil || idx < 0 {
+				return nil, fmt.Errorf("无效的数组下标 %q", rest[1:end])
+			}
+			steps = append(steps, jsonPathStep{index: idx, isIdx: true})
+			rest = rest[end+1:]
+		case rest[0] == '.':
+			rest = rest[1:]
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			steps = append(steps, jsonPathStep{key: rest[:end]})
+			rest = rest[end:]
+		}
+	}
+	return steps, nil
+}
+
+// check 在已解码的 JSON 文档上验证断言
+func (a jsonAssertion) check(doc any) error {
+	v := doc
+	for _, step := range a.path {
+		switch node := v.(type) {
+		case map[string]any:
+			if step.isIdx {
+				return fmt.Errorf("%s: 对象不能使用下标 [%d]", a.raw, step.index)
+			}
+			child, ok := node[step.key]
+			if !ok {
+				return fmt.Errorf("%s: 字段 %q 不存在", a.raw, step.key)
+			}
+			v = child
+		case []any:
+			if !step.isIdx {
+				return fmt.Errorf("%s: 数组不能访问字段 %q", a.raw, step.key)
+			}
+			if step.index >= len(node) {
+				return fmt.Errorf("%s: 下标 %d 越界 (长度 %d)", a.raw, step.index, len(node))
+			}
+			v = node[step.index]
+		default:
+			return fmt.Errorf("%s: 无法在标量值上继续访问路径", a.raw)
+		}
+	}
+
+	got, ok := jsonScalarString(v)
+	if !ok {
+		return fmt.Errorf("%s: 实际值不是标量", a.raw)
+	}
+	if !jsonValueEquals(v, got, a.want) {
+		return fmt.Errorf("%s: 实际值为 %s", a.raw, got)
+	}
+	return nil
+}
+
+// jsonScalarString 把标量 JSON 值格式化为字符串，对象和数组返回 false
+func jsonScalarString(v any) (string, bool) {
+	switch val := v.(type) {
+	case nil:
+		return "null", true
+	case string:
+		return val, true
+	case bool:
+		return strconv.FormatBool(val), true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// jsonValueEquals 数字按数值比较 (使 1.0 与 1 相等)，其余按字符串比较
+func jsonValueEquals(v any, got, want string) bool {
+	if n, ok := v.(float64); ok {
+		w, err := strconv.ParseFloat(want, 64)
+		return err == nil && n == w
+	}
+	return got == want
+}
+
+// checkJSONAssertions 解析响应体并依次验证所有断言，返回第一个失败
+func checkJSONAssertions(body []byte, assertions []jsonAssertion) error {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return fmt.Errorf("响应体不是有效的 JSON: %v", err)
+	}
+	for _, a := range assertions {
+		if err := a.check(doc); err != nil {
+			return fmt.Errorf("JSON 断言失败: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONAssertFlagSet(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr string // 为空时期望解析成功
+	}{
+		{".status=pass", ""},
+		{`.status="pass"`, ""},
+		{".checks[0].healthy=true", ""},
+		{".matrix[1][2]=3", ""},
+		{". = null", ""},
+		{".服务.状态=正常", ""},
+		{"status=pass", "必须以 . 开头"},
+		{".status", "格式应为"},
+		{".checks[0=true", "[ 未闭合"},
+		{".checks[=true", "[ 未闭合"},
+		{".checks[]=true", "无效的数组下标"},
+		{".checks[-1]=true", "无效的数组下标"},
+		{".checks[a].ok=true", "无效的数组下标"},
+	}
+	for _, tt := range tests {
+		var f jsonAssertFlag
+		err := f.Set(tt.value)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("Set(%q) 失败: %v", tt.value, err)
+			} else if len(f) != 1 || f.String() != tt.value {
+				t.Errorf("Set(%q) 后 String() = %q", tt.value, f.String())
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Set(%q) 错误 = %v, 期望包含 %q", tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestJSONAssertionCheck(t *testing.T) {
+	body := []byte(`{
+		"status": "pass",
+		"version": 2,
+		"ratio": 1.0,
+		"owner": null,
+		"checks": [{"name": "db", "healthy": true}, {"name": "缓存", "healthy": false}],
+		"matrix": [[1, 2], [3, 4, 5]],
+		"服务": {"状态": "正常"}
+	}`)
+	tests := []struct {
+		assertion string
+		wantErr   string // 为空时期望断言通过
+	}{
+		{".status=pass", ""},
+		{`.status="pass"`, ""},
+		{".version=2", ""},
+		{".version=2.0", ""},
+		{".ratio=1", ""},
+		{".owner=null", ""},
+		{".checks[0].healthy=true", ""},
+		{".checks[1].name=缓存", ""},
+		{".matrix[1][2]=5", ""},
+		{".服务.状态=正常", ""},
+		{".status=fail", "实际值为 pass"},
+		{".version=3", "实际值为 2"},
+		{".missing=1", `字段 "missing" 不存在`},
+		{".checks[0].missing=1", `字段 "missing" 不存在`},
+		{".checks[2].healthy=true", "下标 2 越界 (长度 2)"},
+		{".matrix[0][2]=1", "下标 2 越界 (长度 2)"},
+		{".status[0]=p", "无法在标量值上继续访问路径"},
+		{".checks.name=db", `数组不能访问字段 "name"`},
+		{".服务[0]=正常", "对象不能使用下标 [0]"},
+		{".checks=[]", "实际值不是标量"},
+	}
+	for _, tt := range tests {
+		a, err := parseJSONAssertion(tt.assertion)
+		if err != nil {
+			t.Fatalf("parseJSONAssertion(%q): %v", tt.assertion, err)
+		}
+		err = checkJSONAssertions(body, []jsonAssertion{a})
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.assertion, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: 错误 = %v, 期望包含 %q", tt.assertion, err, tt.wantErr)
+		}
+	}
+
+	if err := checkJSONAssertions([]byte("not json"), nil); err == nil || !strings.Contains(err.Error(), "不是有效的 JSON") {
+		t.Errorf("非 JSON 响应体: 错误 = %v", err)
+	}
+}
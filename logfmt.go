@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// logfmtSink 实现 -o logfmt: 每个结果一行 key=value，运行结束时每个目标一行统计。
+// 所有行使用固定的键并在末尾附加 -tag 标签，包含空格、等号或引号的值加引号转义。
+type logfmtSink struct {
+	f    *os.File
+	tags tagFlag
+}
+
+func init() {
+	registerSink("logfmt", sinkFormat{
+		help:   "每行 key=value",
+		create: fileSink(func(f *os.File, tags tagFlag) OutputSink { return &logfmtSink{f: f, tags: tags} }),
+	})
+}
+
+// logfmtLine 按顺序拼接键值对
+type logfmtLine []string
+
+func (l *logfmtLine) add(key, value string) {
+	if value == "" || strings.ContainsAny(value, " =\"\t\n") {
+		value = strconv.Quote(value)
+	}
+	*l = append(*l, key+"="+value)
+}
+
+func (l *logfmtLine) addTags(tags tagFlag) {
+	for _, t := range tags {
+		l.add(t.key, t.value)
+	}
+}
+
+func (l logfmtLine) String() string {
+	return strings.Join(l, " ") + "\n"
+}
+
+// logfmtDuration 把时长统一格式化为毫秒 (如 12.345ms)，避免 Go 时长写法中的 µs
+func logfmtDuration(d time.Duration) string {
+	return strconv.FormatFloat(durationMs(d), 'f', 3, 64) + "ms"
+}
+
+func (s *logfmtSink) WriteHeader([]string, string) error { return nil }
+
+func (s *logfmtSink) WriteResult(result PingResult, seq int) error {
+	var l logfmtLine
+	l.add("ts", result.Timestamp.Format(time.RFC3339Nano))
+	l.add("type", "result")
+	l.add("seq", strconv.Itoa(seq))
+	l.add("target", result.Target)
+	l.add("name", result.Name)
+	l.add("ok", strconv.FormatBool(result.Success))
+	l.add("rtt", logfmtDuration(result.ResponseTime))
+	l.add("ttfb", logfmtDuration(result.TTFB))
+	l.add("total", logfmtDuration(result.TotalTime))
+	l.add("code", strconv.Itoa(result.StatusCode))
+	l.add("retries", strconv.Itoa(result.Retries))
+	errText := ""
+	if result.Error != nil {
+		errText = result.Error.Error()
+	}
+	l.add("category", classifyError(result.Error))
+	l.add("error", errText)
+	l.add("trace_id", result.TraceID)
+	l.add("anomaly", strconv.FormatBool(result.Anomaly))
+	l.addTags(s.tags)
+	_, err := s.f.WriteString(l.String())
+	return err
+}
+
+func (s *logfmtSink) EndRound([]PingResult, int) error { return nil }
+
+func (s *logfmtSink) WriteSummary(targets []string, stats []*targetStats) error {
+	now := time.Now().Format(time.RFC3339Nano)
+	for i, t := range targets {
+		sum := stats[i].summary()
+		var l logfmtLine
+		l.add("ts", now)
+		l.add("type", "summary")
+		l.add("target", t)
+		l.add("name", stats[i].name)
+		l.add("sent", strconv.Itoa(sum.Sent))
+		l.add("success", strconv.Itoa(sum.Success))
+		l.add("failed", strconv.Itoa(sum.Failed))
+		l.add("loss", strconv.FormatFloat(sum.LossPercent, 'f', 1, 64))
+		l.add("avg", logfmtDuration(sum.Avg))
+		l.add("min", logfmtDuration(sum.Min))
+		l.add("max", logfmtDuration(sum.Max))
+		l.add("p99", logfmtDuration(sum.P99))
+		l.add("health", healthOf(sum.SuccessRate()).key)
+		l.addTags(s.tags)
+		if _, err := s.f.WriteString(l.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *logfmtSink) Close() error { return closeOutputFile(s.f) }
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxBannerLen 是结果中保留的问候语长度上限
+const maxBannerLen = 200
+
+// mailSession 是一次邮件协议对话: 读取问候语、发送检查命令，按需通过 STARTTLS 升级连接
+type mailSession struct {
+	conn   net.Conn
+	tp     *textproto.Conn
+	host   string // SNI 和证书主机名校验使用的原始主机名
+	opts   *Options
+	banner string
+	tls    *tls.ConnectionState // STARTTLS 之后的连接状态
+}
+
+// startTLS 在当前连接上完成 TLS 握手，之后的对话使用加密连接
+func (s *mailSession) startTLS() error {
+	tc := tls.Client(s.conn, s.opts.TLSVerify.clientConfig(s.host))
+	if err := tc.Handshake(); err != nil {
+		return fmt.Errorf("STARTTLS 握手失败: %w", err)
+	}
+	state := tc.ConnectionState()
+	s.tls = &state
+	s.tp = textproto.NewConn(tc)
+	return nil
+}
+
+// setBanner 记录问候语，过长时截断
+func (s *mailSession) setBanner(line string) {
+	line = strings.TrimSpace(line)
+	if len(line) > maxBannerLen {
+		line = line[:maxBannerLen] + "..."
+	}
+	s.banner = line
+}
+
+// mailHelloName 是 SMTP EHLO 中报告的本机名称
+func mailHelloName() string {
+	if name, err := os.Hostname(); err == nil && name != "" {
+		return name
+	}
+	return "localhost"
+}
+
+// smtpSession: 问候 220 -> EHLO 250 [-> STARTTLS 220 -> EHLO 250] -> QUIT
+func smtpSession(s *mailSession) error {
+	_, banner, err := s.tp.ReadResponse(220)
+	s.setBanner(strings.SplitN(banner, "\n", 2)[0])
+	if err != nil {
+		return fmt.Errorf("问候语: %w", err)
+	}
+	ehlo := func() (string, error) {
+		_, msg, err := smtpCmd(s.tp, 250, "EHLO %s", mailHelloName())
+		if err != nil {
+			return "", fmt.Errorf("EHLO: %w", err)
+		}
+		return msg, nil
+	}
+	ext, err := ehlo()
+	if err != nil {
+		return err
+	}
+	if s.opts.StartTLS {
+		if !strings.Contains(strings.ToUpper(ext), "STARTTLS") {
+			return errors.New("服务器没有声明 STARTTLS 能力")
+		}
+		if _, _, err := smtpCmd(s.tp, 220, "STARTTLS"); err != nil {
+			return fmt.Errorf("STARTTLS: %w", err)
+		}
+		if err := s.startTLS(); err != nil {
+			return err
+		}
+		if _, err := ehlo(); err != nil {
+			return err
+		}
+	}
+	smtpCmd(s.tp, 221, "QUIT")
+	return nil
+}
+
+// smtpCmd 发送一条 SMTP 命令并读取 (可能多行的) 回复，回复码不是 code 时返回错误
+func smtpCmd(tp *textproto.Conn, code int, format string, args ...any) (int, string, error) {
+	id, err := tp.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	tp.StartResponse(id)
+	defer tp.EndResponse(id)
+	return tp.ReadResponse(code)
+}
+
+// imapSession: 问候 * OK -> NOOP [-> STARTTLS -> NOOP] -> LOGOUT
+func imapSession(s *mailSession) error {
+	line, err := s.tp.ReadLine()
+	s.setBanner(line)
+	if err != nil {
+		return fmt.Errorf("问候语: %w", err)
+	}
+	if !strings.HasPrefix(line, "* OK") && !strings.HasPrefix(line, "* PREAUTH") {
+		return fmt.Errorf("问候语不是 * OK: %s", s.banner)
+	}
+	seq := 0
+	cmd := func(command string) error {
+		seq++
+		tag := fmt.Sprintf("p%d", seq)
+		if err := s.tp.PrintfLine("%s %s", tag, command); err != nil {
+			return err
+		}
+		for {
+			line, err := s.tp.ReadLine()
+			if err != nil {
+				return fmt.Errorf("%s: %w", command, err)
+			}
+			// 带标签的行是命令的最终结果，之前的 * 行是附带的状态更新
+			if rest, ok := strings.CutPrefix(line, tag+" "); ok {
+				if !strings.HasPrefix(rest, "OK") {
+					return fmt.Errorf("%s: %s", command, rest)
+				}
+				return nil
+			}
+		}
+	}
+	if err := cmd("NOOP"); err != nil {
+		return err
+	}
+	if s.opts.StartTLS {
+		if err := cmd("STARTTLS"); err != nil {
+			return err
+		}
+		if err := s.startTLS(); err != nil {
+			return err
+		}
+		if err := cmd("NOOP"); err != nil {
+			return err
+		}
+	}
+	cmd("LOGOUT")
+	return nil
+}
+
+// pop3Session: 问候 +OK [-> STLS -> +OK] -> QUIT +OK。
+// 登录前的 AUTHORIZATION 状态不允许 NOOP，以 QUIT 的 +OK 作为检查命令
+func pop3Session(s *mailSession) error {
+	line, err := s.tp.ReadLine()
+	s.setBanner(line)
+	if err != nil {
+		return fmt.Errorf("问候语: %w", err)
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("问候语不是 +OK: %s", s.banner)
+	}
+	cmd := func(command string) error {
+		if err := s.tp.PrintfLine("%s", command); err != nil {
+			return err
+		}
+		line, err := s.tp.ReadLine()
+		if err != nil {
+			return fmt.Errorf("%s: %w", command, err)
+		}
+		if !strings.HasPrefix(line, "+OK") {
+			return fmt.Errorf("%s: %s", command, line)
+		}
+		return nil
+	}
+	if s.opts.StartTLS {
+		if err := cmd("STLS"); err != nil {
+			return err
+		}
+		if err := s.startTLS(); err != nil {
+			return err
+		}
+	}
+	return cmd("QUIT")
+}
+
+// mailSessions 是各邮件 ping 类型的对话实现
+var mailSessions = map[string]func(*mailSession) error{
+	"smtp": smtpSession,
+	"imap": imapSession,
+	"pop3": pop3Session,
+}
+
+// pingMail 在 TCP 连接的基础上完成邮件协议对话，响应时间包含连接、问候语、
+// 检查命令和 STARTTLS 握手，-v 显示服务器的问候语
+func pingMail(target string, opts *Options) PingResult {
+	result := PingResult{Target: target}
+	session := mailSessions[strings.ToLower(opts.PingType)]
+
+	target = withDefaultPort(target, opts.defaultPort())
+	host, _, err := net.SplitHostPort(target)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	start := time.Now()
+	raw, err := opts.dial("tcp", target)
+	if err != nil {
+		result.ResponseTime = time.Since(start)
+		result.Error = err
+		return result
+	}
+	conn := &countingConn{Conn: raw}
+	defer conn.Close()
+	result.RemoteAddr = conn.RemoteAddr().String()
+	if err := conn.SetDeadline(start.Add(opts.Timeout)); err != nil {
+		result.Error = err
+		return result
+	}
+
+	s := &mailSession{conn: conn, tp: textproto.NewConn(conn), host: host, opts: opts}
+	err = session(s)
+	result.ResponseTime = time.Since(start)
+	result.Banner = s.banner
+	result.BytesSent, result.BytesRecv = conn.sent.Load(), conn.received.Load()
+	if err != nil {
+		result.Error = explainReset(err)
+		return result
+	}
+
+	if s.tls != nil && len(s.tls.PeerCertificates) > 0 {
+		result.CertNotAfter = s.tls.PeerCertificates[0].NotAfter
+		result.TLSReport = opts.TLSVerify.report(s.tls, host)
+	}
+	if len(opts.Pins) > 0 {
+		if err := opts.Pins.check(s.tls); err != nil {
+			result.Error = err
+			return result
+		}
+	}
+	result.Success = true
+	return result
+}
@@ -1,13 +1,32 @@
 package main
 
 import (
+	"cmp"
 	"flag"
 	"fmt"
+	"io"
+	"maps"
+	"math"
+	"math/rand/v2"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// nearTimeoutRatio 响应时间达到超时时间的该比例即视为接近超时
+	nearTimeoutRatio = 0.8
+	// nearTimeoutWarnShare 接近超时的样本占比达到该值时在统计中提示调大 -timeout
+	nearTimeoutWarnShare = 0.1
 )
 
 const (
@@ -16,211 +35,1886 @@ const (
 	ColorGreen  = "\033[32m"
 	ColorYellow = "\033[33m"
 	ColorBlue   = "\033[34m"
+	ColorPurple = "\033[35m"
 	ColorCyan   = "\033[36m"
 )
 
 type PingResult struct {
-	Target      string
-	Success     bool
-	ResponseTime time.Duration
-	StatusCode  int
-	Error       error
+	Target           string
+	Name             string    // -name 或配置文件中为检查指定的名称
+	Timestamp        time.Time // 发起探测的时间
+	Success          bool
+	ResponseTime     time.Duration
+	StatusCode       int
+	Error            error
+	Retries          int
+	ServerTime       time.Duration // Server-Timing 头报告的服务端处理时间，0 表示未提供
+	Corrupted        bool          // ICMP 收到回复但回显数据不一致
+	BytesSent        int64         // 本次探测实际写入网络的字节数 (HTTPS 包含 TLS 开销)
+	BytesRecv        int64         // 本次探测实际从网络读取的字节数
+	NearTimeout      bool          // 响应时间已接近 -timeout 上限，结果可能被超时截断
+	Anomaly          bool          // 响应时间超过本次运行 p95 × -anomaly-factor
+	AnomalyThreshold time.Duration // 判定延迟异常时的阈值
+	ConnReused       bool          // HTTP 请求复用了已有连接 (-keepalive)，或复用了保持的 WebSocket 连接
+	CertNotAfter     time.Time     // HTTPS 服务端证书的过期时间
+	ConnectTime      time.Duration // -probe-both 中单独 TCP 连接测试的耗时，或新建 WebSocket 连接的握手耗时
+	RemoteAddr       string        // 实际响应的远端地址 (DNS 轮询或 CDN 时区分后端)
+	BodyBytes        int64         // -verify-body 完整读取的响应体字节数 (解码后)
+	WireBytes        int64         // 读取响应体时实际传输的字节数 (解码前)
+	Encoding         string        // 响应的 Content-Encoding，如 gzip
+	Chunked          bool          // 响应使用 chunked 传输编码
+	Trailer          http.Header   // -verify-body 读完响应体后收到的 trailer
+	TLSReport        string        // HTTPS 各项证书校验的结果
+	TTFB             time.Duration // HTTP 从发起请求到收到响应第一个字节的时间
+	TotalTime        time.Duration // HTTP 读完响应体的总耗时，未读取响应体时为 0
+	QUICVersion      string        // quic 探测协商的 QUIC 版本
+	ALPN             string        // quic 探测协商的应用层协议
+	Resumed          bool          // quic 握手恢复了上一次探测的 TLS 会话
+	Used0RTT         bool          // quic 握手使用了 0-RTT
+	ClockSkew        time.Duration // 根据 Date 响应头估算的服务器时钟偏差 (正值为服务器偏快)，0 表示未提供
+	TraceID          string        // -trace-header 写入请求的关联 ID
+	Banner           string        // 邮件服务器的问候语 (smtp/imap/pop3)、memcached 或数据库的版本
+	Stages           []stageResult // -probe-order 各阶段的结果，执行到第一个失败的阶段为止
+	Proxy            string        // -proxy-list 中本次探测经由的代理
+	CNAMEs           []string      // -strict-dns 查询应答中的 CNAME 链
+	Chain            *chainReport  // -dump-chain 记录的服务器证书链，证书校验失败时同样记录
+	Family           string        // 指定 -resolve-family 时实际连接的协议族 (ipv4/ipv6)
+}
+
+// Options 保存影响 ping 行为和输出的命令行选项
+type Options struct {
+	PingType  string
+	Timeout   time.Duration
+	Check     *checkExpr // HTTP 自定义成功条件，nil 表示使用默认规则 (状态码 < 500)
+	Script    *checkExpr // -check-script 脚本，对所有 ping 类型覆盖内置判定
+	Verbose   bool
+	Resolve   resolveFlag       // -resolve 指定的地址覆盖
+	Hosts     map[string]string // -hostfile 中的主机名到 IP 映射
+	SSH       *ssh.Client       // 非 nil 时 TCP/HTTP 连接通过 SSH 跳板机转发
+	Proxies   *proxyRotation    // -proxy-list 轮换使用的代理，nil 表示直接连接
+	StrictDNS bool              // 探测前直接查询 DNS，区分 NXDOMAIN、SERVFAIL 等失败
+	DNSServer string            // -strict-dns 查询的服务器 host:port
+	Family    string            // -resolve-family: 空 (auto)、4、6 或 dual
+	proxy     *proxyEntry       // 本次探测经由的代理，由 Proxies 为每个代理派生的选项设置
+
+	NoRedirectOK  bool             // 将 3xx 视为失败
+	ExpectJSON    jsonAssertFlag   // 对 JSON 响应体字段的断言
+	ExpectHeader  headerAssertFlag // 对响应头的断言
+	Pins          pinFlag          // 证书公钥 SHA-256 指纹，任一匹配即通过
+	TLSVerify     tlsVerify        // -verify 启用的 TLS 校验项
+	DumpChain     bool             // 记录并检查服务器发送的完整证书链
+	KeepAlive     bool             // 在多次 HTTP 请求之间复用连接
+	VerifyBody    bool             // 完整读取响应体，传输中断视为失败
+	ProbeBoth     bool             // HTTP 请求前先单独测试 TCP 端口
+	ConnectOnly   bool             // 只建立连接和 TLS 握手，不发送 HTTP 请求
+	ProbeOrder    []string         // -probe-order 依次执行的阶段，为空时只做单次探测
+	RetryUnsafe   bool             // 允许重试非幂等的 HTTP 方法
+	StartTLS      bool             // smtp/imap/pop3/mysql/postgres 在协议内升级为 TLS 连接
+	RedisUser     string           // redis AUTH 的 ACL 用户名
+	RedisPassword string           // redis AUTH 的密码，为空时读取 REDISCLI_AUTH 环境变量
+	PostgresUser  string           // postgres 启动消息中的用户名，只用于让服务器回复认证方式
+	ReadBody      string           // -read-body: none、headers 或 full，决定响应时间的测量终点
+	ReadLimit     int64            // -read-body full 最多读取的响应体字节数，0 表示不限制
+	TraceHeader   string           // 写入关联 ID 的请求头 (规范化名称)，空表示不写入
+	MaxSkew       time.Duration    // Date 响应头与本机时间的偏差超过该值时视为失败，0 表示不检查
+
+	Method      string // HTTP 请求方法
+	BodyFile    string // HTTP 请求体文件，每次请求重新打开并流式发送
+	ContentType string // HTTP 请求的 Content-Type
+
+	IfNoneMatch     string       // If-None-Match 请求头
+	IfModifiedSince string       // If-Modified-Since 请求头 (HTTP 日期格式)
+	ExpectStatus    map[int]bool // 非空时只有这些状态码算成功，代替状态码 < 500 的默认规则
+
+	SendData   []byte // TCP/UDP 连接建立后发送的内容 (-send-file)
+	ExpectData []byte // 回复开头必须匹配的内容 (-expect-file)
+	Linger     int    // TCP 探测关闭连接时的 SO_LINGER 秒数，-1 表示使用系统默认的关闭方式
+
+	ICMPSize    int    // ICMP 回显数据长度
+	ICMPPattern []byte // ICMP 回显数据填充内容，nil 表示递增字节
+
+	Port string // -port 指定的端口，目标未写端口时使用
+}
+
+// defaultPorts 是各 ping 类型的默认端口，未列出的类型为 80
+var defaultPorts = map[string]string{
+	"https":     "443",
+	"quic":      "443",
+	"smtp":      "25",
+	"imap":      "143",
+	"pop3":      "110",
+	"redis":     "6379",
+	"memcached": "11211",
+	"mysql":     "3306",
+	"postgres":  "5432",
+	"wss":       "443",
+}
+
+// defaultPort 返回目标未写端口时使用的端口: -port 优先，否则按 ping 类型
+func (o *Options) defaultPort() string {
+	if o.Port != "" {
+		return o.Port
+	}
+	if port, ok := defaultPorts[strings.ToLower(o.PingType)]; ok {
+		return port
+	}
+	return "80"
+}
+
+// needBody 判断是否有断言需要读取 HTTP 响应体
+func (o *Options) needBody() bool {
+	return len(o.ExpectJSON) > 0 || (o.Check != nil && o.Check.needBody) || (o.Script != nil && o.Script.needBody)
 }
 
-func main() {
-	// 命令行参数
-	target := flag.String("t", "", "目标地址 (必需)")
-	pingType := flag.String("type", "http", "Ping 类型: http, https, tcp, icmp")
-	count := flag.Int("c", 4, "Ping 次数")
-	timeout := flag.Int("timeout", 5, "超时时间(秒)")
-	interval := flag.Int("i", 1, "每次 ping 间隔(秒)")
-	continuous := flag.Bool("continuous", false, "持续 ping (Ctrl+C 停止)")
-	//测试
-	flag.Parse()
+// targetStats 汇总单个目标在整个运行期间的结果。汇总指标随每个结果增量更新，
+// 逐条结果只在环形缓冲区中保留最近 -max-samples 个，长时间运行时内存占用有上限。
+type targetStats struct {
+	name   string // 检查名称，输出中代替目标地址
+	family string // -all-ips 展开的检查所连接地址的协议族 (ipv4/ipv6)，其余为空
+
+	proxies []string                // -proxy-list 中各代理的名称，非空时按代理分别统计
+	byProxy map[string]*targetStats // 经由每个代理的结果的统计
+
+	recent     []PingResult // 最近的结果，写满后按环形缓冲区覆盖最旧的
+	next       int          // 缓冲区写满后下一个覆盖的位置
+	maxSamples int          // 0 表示不限制
+
+	timeoutAsFailure bool // 超时是否计入丢包率
+
+	sum      summaryStats
+	total    time.Duration // 成功响应的总耗时
+	mean, m2 float64       // Welford 算法的均值和平方差累计 (纳秒)
+
+	p50, p90, p99 *p2Quantile // 成功响应时间的流式分位数估计
+
+	anomaly *anomalyDetector // 非 nil 时启用 -anomaly-factor
+}
+
+func newTargetStats(maxSamples int, timeoutAsFailure bool) *targetStats {
+	return &targetStats{
+		maxSamples:       maxSamples,
+		timeoutAsFailure: timeoutAsFailure,
+		p50:              newP2Quantile(0.5),
+		p90:              newP2Quantile(0.9),
+		p99:              newP2Quantile(0.99),
+	}
+}
+
+func (s *targetStats) add(result PingResult) {
+	if s.maxSamples <= 0 || len(s.recent) < s.maxSamples {
+		s.recent = append(s.recent, result)
+	} else {
+		s.recent[s.next] = result
+		s.next = (s.next + 1) % s.maxSamples
+	}
+
+	if s.proxies != nil && result.Proxy != "" {
+		if s.byProxy == nil {
+			s.byProxy = make(map[string]*targetStats)
+		}
+		sub := s.byProxy[result.Proxy]
+		if sub == nil {
+			sub = newTargetStats(1, s.timeoutAsFailure)
+			s.byProxy[result.Proxy] = sub
+		}
+		sub.add(result)
+	}
+
+	sum := &s.sum
+	sum.Sent++
+	sum.Retries += result.Retries
+	sum.BytesSent += result.BytesSent
+	sum.BytesRecv += result.BytesRecv
+	if result.NearTimeout {
+		sum.NearTimeout++
+	}
+	if result.Anomaly {
+		sum.Anomalies++
+	}
+	if result.ConnReused {
+		sum.ConnReused++
+	}
+	if result.Corrupted {
+		sum.Corrupted++
+	}
+	if !result.Success {
+		sum.Failed++
+		switch category := classifyError(result.Error); {
+		case category == errCategoryTimeout:
+			sum.Timeouts++
+		case isDNSCategory(category):
+			if sum.DNSFailures == nil {
+				sum.DNSFailures = make(map[string]int)
+			}
+			sum.DNSFailures[category]++
+		}
+		return
+	}
+
+	sum.Success++
+	rt := result.ResponseTime
+	s.total += rt
+	if sum.Success == 1 || rt < sum.Min {
+		sum.Min = rt
+	}
+	if rt > sum.Max {
+		sum.Max = rt
+	}
+	delta := float64(rt) - s.mean
+	s.mean += delta / float64(sum.Success)
+	s.m2 += delta * (float64(rt) - s.mean)
+	s.p50.add(rt)
+	s.p90.add(rt)
+	s.p99.add(rt)
+}
+
+// samples 按时间顺序返回缓冲区中保留的最近 n 个结果，n <= 0 时返回全部
+func (s *targetStats) samples(n int) []PingResult {
+	ordered := append(slices.Clone(s.recent[s.next:]), s.recent[:s.next]...)
+	if n > 0 && n < len(ordered) {
+		ordered = ordered[len(ordered)-n:]
+	}
+	return ordered
+}
+
+// last 返回最近一次结果
+func (s *targetStats) last() (PingResult, bool) {
+	if len(s.recent) == 0 {
+		return PingResult{}, false
+	}
+	i := len(s.recent) - 1
+	if s.next > 0 {
+		i = s.next - 1
+	}
+	return s.recent[i], true
+}
+
+// summaryStats 是由 targetStats 计算出的汇总指标
+type summaryStats struct {
+	Sent        int
+	Success     int
+	Failed      int
+	Timeouts    int     // 失败中属于超时的次数
+	LossPercent float64 // 关闭 -timeout-as-failure 时不包含超时；数据损坏收到了回复，不计入丢包
+	Avg         time.Duration
+	Min         time.Duration
+	Max         time.Duration
+	StdDev      time.Duration // 成功响应时间的标准差
+	P50         time.Duration // 成功响应时间的分位数 (P² 流式估计)
+	P90         time.Duration
+	P99         time.Duration
+	Retries     int
+	Corrupted   int     // 收到回复但回显数据不一致的次数，计入 Failed
+	CorruptRate float64 // 数据损坏占发送次数的百分比
+	BytesSent   int64
+	BytesRecv   int64
+	NearTimeout int
+	ConnReused  int
+	Anomalies   int            // -anomaly-factor 标记的延迟异常次数
+	DNSFailures map[string]int // -strict-dns 各失败类别的次数
+}
+
+func (s *targetStats) summary() summaryStats {
+	sum := s.sum
+	sum.DNSFailures = maps.Clone(sum.DNSFailures)
+	if sum.Sent > 0 {
+		lost := sum.Failed - sum.Corrupted
+		if !s.timeoutAsFailure {
+			lost -= sum.Timeouts
+		}
+		sum.LossPercent = float64(lost) / float64(sum.Sent) * 100
+		sum.CorruptRate = float64(sum.Corrupted) / float64(sum.Sent) * 100
+	}
+	if sum.Success > 0 {
+		sum.Avg = s.total / time.Duration(sum.Success)
+	}
+	if sum.Success > 1 {
+		sum.StdDev = time.Duration(math.Sqrt(s.m2 / float64(sum.Success-1)))
+	}
+	sum.P50, sum.P90, sum.P99 = s.p50.value(), s.p90.value(), s.p99.value()
+	return sum
+}
+
+// SuccessRate 返回成功率百分比，与丢包率一致地处理超时
+func (s summaryStats) SuccessRate() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return 100 - s.LossPercent
+}
+
+// healthLevel 是根据成功率评估出的服务健康状态
+type healthLevel struct {
+	label string // 文本输出使用的中文描述
+	key   string // 结构化输出使用的稳定标识
+	color string
+}
+
+// healthOf 根据成功率评估健康状态
+func healthOf(successRate float64) healthLevel {
+	switch {
+	case successRate == 100:
+		return healthLevel{"优秀", "excellent", ColorGreen}
+	case successRate >= 90:
+		return healthLevel{"良好", "good", ColorGreen}
+	case successRate >= 70:
+		return healthLevel{"一般", "fair", ColorYellow}
+	default:
+		return healthLevel{"较差", "poor", ColorRed}
+	}
+}
+
+func main() {
+	// 命令行参数
+	target := flag.String("t", "", "目标地址 (必需)，多个目标用逗号分隔")
+	pingType := flag.String("type", "http", "Ping 类型: http, https, tcp, udp, icmp, quic, smtp, imap, pop3, redis, memcached, mysql, postgres, ws, wss")
+	count := flag.Int("c", 4, "Ping 次数")
+	timeout := flag.Int("timeout", 5, "超时时间(秒)")
+	interval := flag.Int("i", 1, "每次 ping 间隔(秒)")
+	hold := flag.Duration("hold", 0, "TCP 连接保持模式: 建立一个连接并保持该时长，每隔 -i 秒写入少量数据，报告连接存活了多久 (诊断空闲超时)")
+	runFor := flag.Duration("for", 0, "持续 ping 指定时长后停止 (如 30s、5m)，忽略 -c")
+	continuous := flag.Bool("continuous", false, "持续 ping (Ctrl+C 停止，发送 SIGUSR1 暂停/恢复，Ctrl+\\ 或 Ctrl+T 打印当前统计)")
+	adaptive := flag.Bool("interval-adaptive", false, "自适应间隔: 状态变化时缩短到 -interval-min，稳定时逐步延长到 -interval-max")
+	intervalMin := flag.Duration("interval-min", 200*time.Millisecond, "自适应间隔的下限")
+	intervalMax := flag.Duration("interval-max", 30*time.Second, "自适应间隔的上限")
+	retries := flag.Int("retry", 0, "每次 ping 失败后的最大重试次数")
+	anomalyFactor := flag.Float64("anomaly-factor", 0, "响应时间超过本次运行已有样本 p95 的该倍数时标记为延迟异常 (如 3)，0 表示不检测")
+	anomalyWarmup := flag.Int("anomaly-warmup", 20, "-anomaly-factor 开始判断前需要的成功样本数")
+	redisUser := flag.String("redis-user", "", "redis AUTH 使用的 ACL 用户名 (Redis 6+)")
+	redisPassword := flag.String("redis-password", "", "redis AUTH 使用的密码 (默认读取 REDISCLI_AUTH 环境变量，避免密码出现在进程列表中)")
+	startTLS := flag.Bool("starttls", false, "smtp/imap/pop3 在检查命令之后通过 STARTTLS (POP3 为 STLS) 升级为 TLS，mysql/postgres 通过 SSLRequest 升级，并按 -verify 校验证书")
+	postgresUser := flag.String("pg-user", "postgres", "postgres 握手探测在启动消息中使用的用户名 (不会进行认证)")
+	retryUnsafe := flag.Bool("retry-unsafe", false, "允许重试 POST、PATCH 等非幂等的 HTTP 方法 (默认只在请求没有发出时重试，避免重复提交)")
+	retryBudgetSize := flag.Int("retry-budget", -1, "整个运行期间的重试总预算 (-1 表示不限制)")
+	check := flag.String("check", "", `HTTP 成功条件表达式，如 'status==200 && latency<200ms && body contains "ok"'`)
+	checkScript := flag.String("check-script", "", "从文件读取检查脚本 (语法同 -check，支持 # 注释)，对所有 ping 类型覆盖内置的成功判定")
+	verbose := flag.Bool("v", false, "详细输出")
+	human := flag.Bool("human", false, "文本输出中的时长按量级自动选择 ns/µs/ms/s 单位 (三位有效数字)，字节数使用 KiB/MiB")
+	si := flag.Bool("si", false, "字节数使用十进制单位 KB/MB (1000 进制)，隐含 -human")
+	maxDownload := flag.String("max-download", "0", "所有探测累计接收的字节数达到上限时停止并输出统计，支持 KB/MB/GB 后缀，用于按流量计费的网络 (0 表示不限制)")
+	maxFailures := flag.Int("max-failures", 0, "所有目标累计失败达到 K 次时停止并以退出码 1 结束，用于部署门禁 (0 表示不限制)")
+	abortOnStatus := flag.String("abort-on-status", "", "出现指定 HTTP 状态码时立即停止并输出统计，多个用逗号分隔 (如 503)")
+	resolve := resolveFlag{}
+	flag.Var(resolve, "resolve", "将主机解析固定到指定 IP，格式 host:port:addr 或 host:addr，IPv6 地址需加方括号 (host:port:[v6])，可重复")
+	resolver := flag.String("resolver", "", "DNS 解析器: go (Go 内置，读取 resolv.conf 直接查询) 或 cgo (系统 getaddrinfo，遵循 nsswitch.conf)，默认自动选择")
+	hostFile := flag.String("hostfile", "", "使用自定义 hosts 文件解析目标 (优先于 DNS，-resolve 优先于它)")
+	checkNames := flag.String("name", "", "检查名称，输出中代替目标地址显示 (JSON/CSV/logfmt 另有 name 字段)，多个目标时用逗号分隔并与 -t 一一对应")
+	srv := flag.Bool("srv", false, "将 -t 视为 SRV 记录名 (如 _http._tcp.example.com)，依次 ping 解析出的每个 host:port")
+	failTemplate := flag.String("fail-template", "", "失败消息的 Go 模板，可用 PingResult 字段及 .Seq、.Label、.Category，如 '{{.Label}} 失败: {{.Error}}' (ms 函数把时长转为毫秒)")
+	recordPath := flag.String("record", "", "把全部结果录制到文件，供 -replay 回放")
+	replayPath := flag.String("replay", "", "不探测目标，按原始时间间隔回放 -record 录制的结果 (使用当前的输出选项)")
+	replayInstant := flag.Bool("replay-instant", false, "-replay 时不等待，立即输出全部结果")
+	resolveFamily := flag.String("resolve-family", "auto", "连接使用的协议族: auto (系统默认), 4 (只用 IPv4), 6 (只用 IPv6), dual (RFC 8305 Happy Eyeballs，IPv6 和 IPv4 错开竞速)")
+	resolveOnce := flag.Bool("resolve-once", false, "启动时把主机名解析一次并在整个运行期间固定连接该地址 (默认每次探测都重新解析，能反映 DNS 变化)")
+	allIPs := flag.Bool("all-ips", false, "把主机名目标展开为每个 A/AAAA 地址一个检查，分别统计延迟和丢包 (Host 头和 SNI 仍使用主机名)")
+	ifNoneMatch := flag.String("if-none-match", "", "发送 If-None-Match 条件请求头 (ETag，如 '\"abc123\"')，配合 -expect-status 304 验证缓存")
+	ifModifiedSince := flag.String("if-modified-since", "", "发送 If-Modified-Since 条件请求头 (HTTP 日期或 RFC 3339 时间)")
+	expectStatus := flag.String("expect-status", "", "只有这些 HTTP 状态码算成功，多个用逗号分隔 (如 304 或 200,204)，代替状态码 < 500 的默认规则")
+	noRedirectOK := flag.Bool("no-redirect-ok", false, "将任何 3xx 重定向视为失败 (仍不跟随重定向)")
+	probeBoth := flag.Bool("probe-both", false, "HTTP 探测前先单独测试 TCP 端口，分别报告连接和 HTTP 耗时，两者都成功才算成功")
+	traceHeader := flag.String("trace-header", "", "为每次 HTTP 探测生成关联 ID 写入该请求头并记录在输出中 (如 X-Request-ID)；traceparent 生成 W3C Trace Context 头并记录 trace-id")
+	maxSkew := flag.Duration("max-skew", 0, "HTTP 响应 Date 头与本机时间的偏差超过该值时视为失败 (如 5s，Date 精度为 1 秒，没有 Date 头时不检查)，0 表示不检查")
+	probeOrder := flag.String("probe-order", "", "HTTP/HTTPS 按顺序执行的检查阶段，逗号分隔 (dns, tcp, tls, http)，遇到第一个失败的阶段即停止并报告该阶段，-v 显示各阶段耗时")
+	connectOnly := flag.Bool("connect-only", false, "HTTP/HTTPS 只测量建立连接和 TLS 握手的耗时，握手完成后立即取消请求 (不发送请求，不等待服务端处理)")
+	readBody := flag.String("read-body", readBodyHeaders, "HTTP 响应时间的测量终点: none (首字节)、headers (完整响应头) 或 full (下载完响应体，受 -read-limit 限制)")
+	readLimit := flag.String("read-limit", "0", "-read-body full 最多读取的响应体字节数，支持 KB/MB/GB 后缀，0 表示不限制")
+	verifyBody := flag.Bool("verify-body", false, "完整读取 HTTP 响应体，传输中断 (如 chunked 流被截断) 视为失败，-v 时显示 trailer")
+	keepAlive := flag.Bool("keepalive", false, "在多次 HTTP 请求之间复用连接 (统计中显示复用率)")
+	dumpChain := flag.Bool("dump-chain", false, "记录 HTTPS 服务器发送的完整证书链 (-v 时显示每张证书的主题、签发者、有效期和 SAN，JSON 中为 tls_chain)，并指出过期、顺序错误和缺少中间证书等问题")
+	tlsVerifyMode := flag.String("verify", "full", "HTTPS 证书校验: full (全部)、chain (只校验证书链，忽略主机名)、hostname (只校验主机名，允许自签名)、none (不校验)")
+	caCert := flag.String("cacert", "", "只信任该 PEM 文件中的 CA 证书校验证书链 (替换系统根证书)")
+	caCertAppend := flag.String("cacert-append", "", "在系统根证书之外额外信任该 PEM 文件中的 CA 证书 (如内部 CA)")
+	var pins pinFlag
+	var tags tagFlag
+	flag.Var(&tags, "tag", "为 JSON/CSV/logfmt 输出的每条记录 (openmetrics 的每个指标) 附加标签，格式 key=value (如 env=prod)，可重复")
+	flag.Var(&pins, "pin-sha256", "要求 HTTPS 证书链中有证书的公钥 SHA-256 (base64) 与之匹配，可重复 (任一匹配即通过)")
+	var expectHeader headerAssertFlag
+	flag.Var(&expectHeader, "expect-header", "断言响应头，'Name: value' 完全相等，'Name: ~value' 包含，'Name: /re/' 正则匹配，'Name' 仅要求存在，可重复")
+	var expectJSON jsonAssertFlag
+	flag.Var(&expectJSON, "expect-json", "断言 JSON 响应体字段，如 '.status=pass'，可重复")
+	method := flag.String("method", "", "HTTP 请求方法 (默认 GET，指定 -body-file 时默认 POST)")
+	bodyFile := flag.String("body-file", "", "从文件读取 HTTP 请求体 (流式发送)")
+	contentType := flag.String("content-type", "", "HTTP 请求的 Content-Type")
+	linger := flag.Int("linger", -1, "TCP 探测关闭连接时的 SO_LINGER 秒数: 0 表示以 RST 立即关闭，不留下 TIME_WAIT (高频探测时避免耗尽本地端口)，-1 为系统默认")
+	sendFile := flag.String("send-file", "", "TCP/UDP 连接建立后发送文件内容 (如构造好的协议请求，最大 64KB)")
+	expectFile := flag.String("expect-file", "", "TCP/UDP 回复的开头必须与文件内容一致 (最大 64KB)")
+	icmpSize := flag.Int("size", defaultICMPSize, "ICMP 回显数据长度(字节)")
+	icmpPattern := flag.String("pattern", "", "ICMP 回显数据填充内容 (十六进制，如 ff00)")
+	sshSpec := flag.String("ssh", "", "通过 SSH 跳板机转发 TCP/HTTP 探测，格式 user@host[:port] (支持 ssh-agent 和私钥认证)")
+	sshKey := flag.String("ssh-key", "", "SSH 私钥路径 (默认尝试 ~/.ssh/id_ed25519, id_ecdsa, id_rsa)")
+	sshInsecure := flag.Bool("ssh-insecure", false, "不校验 SSH 主机密钥 (known_hosts)")
+	strictDNS := flag.Bool("strict-dns", false, "探测前直接向 DNS 服务器查询 A/AAAA，把 NXDOMAIN、SERVFAIL、空应答和只有 CNAME 的应答分别报告为失败")
+	dnsServerAddr := flag.String("dns-server", "", "-strict-dns 查询的 DNS 服务器 host[:port] (默认 /etc/resolv.conf 的第一个 nameserver)")
+	proxyList := flag.String("proxy-list", "", "代理列表文件 (每行 http:// 或 socks5:// 代理 URL 和可选的名称)，每次探测轮换使用下一个代理，统计按代理分组")
+	output := flag.String("o", "text", "标准输出格式: "+sinkFormatsHelp())
+	jsonOut := flag.String("json-out", "", "同时将 JSON 结果写入文件")
+	csvOut := flag.String("csv", "", "同时将 CSV 结果写入文件")
+	statsdAddr := flag.String("statsd", "", "同时通过 UDP 向 StatsD 发送 ping.rtt (timing) 和 ping.success/ping.failure (counter)，格式 host[:port] (默认端口 8125)")
+	statsdFormat := flag.String("statsd-format", "dogstatsd", "-statsd 的格式: dogstatsd (以 |#target: 标签区分检查) 或 statsd (检查名称拼接到指标名末尾)")
+	influxDest := flag.String("influx", "", "同时输出 InfluxDB line protocol: 文件路径，或 http(s) 写入接口 URL (只给出服务器地址时使用 /api/v2/write)")
+	influxOrg := flag.String("influx-org", "", "-influx 写入接口的组织 (org)")
+	influxBucket := flag.String("influx-bucket", "", "-influx 写入接口的 bucket")
+	influxToken := flag.String("influx-token", "", "-influx 写入接口的令牌 (默认读取 INFLUX_TOKEN 环境变量，避免令牌出现在进程列表中)")
+	var extraSinks sinkFlag
+	flag.Var(&extraSinks, "sink", "同时以指定格式写入输出目标，格式 format=dest (如 logfmt=ping.log)，可重复；format 与 -o 相同")
+	useSyslog := flag.Bool("syslog", false, "同时将结果写入 syslog (成功为 info，失败为 warning/err)")
+	syslogAddr := flag.String("syslog-addr", "", "远程 syslog 地址，格式 host[:port] 或 tcp://host:port (默认本机，隐含 -syslog)")
+	dumpSeries := flag.String("dump-series", "", "把所有成功探测的响应时间 (纳秒) 按顺序逐行写入文件，多个目标的结果混合写入")
+	seriesFormat := flag.String("series-format", "rtt", "-dump-series 的格式: rtt (每行一个数值) 或 timestamp,rtt (Unix 纳秒时间戳,响应时间)")
+	fifoPath := flag.String("fifo", "", "同时把结果以 NDJSON 写入命名管道 (不存在时创建)，消费端跟不上时丢弃事件而不阻塞探测，结束时报告丢弃数")
+	bell := flag.Bool("bell", false, "探测失败时响铃 (向 stderr 输出 \\a)")
+	notify := flag.Bool("notify", false, "探测失败时发送桌面通知 (Linux 使用 notify-send，macOS 使用 osascript，Windows 使用 PowerShell toast)")
+	alertInterval := flag.Duration("alert-interval", time.Minute, "-bell/-notify 对同一检查的最小提醒间隔，持续故障期间不重复提醒，0 表示每次失败都提醒")
+	onFailure := flag.String("on-failure", "", "每次探测失败时执行的 shell 命令，目标和错误通过 PING_TARGET、PING_ERROR 等环境变量传入")
+	onRecovery := flag.String("on-recovery", "", "检查从失败恢复为成功时执行的 shell 命令 (环境变量同 -on-failure，另有 PING_DOWNTIME_SECONDS)")
+	hookTimeout := flag.Duration("hook-timeout", 30*time.Second, "-on-failure/-on-recovery 命令的最长执行时间，超时后终止")
+	statusFilePath := flag.String("status-file", "", "运行结束时将各目标的最终状态以 JSON 原子写入文件")
+	aggregateSize := flag.Int("aggregate", 0, "每 N 个结果输出一行汇总 (丢包率和最小/平均/最大)，代替逐条输出")
+	summaryOnChange := flag.Bool("summary-only-on-change", false, "不输出逐条结果，只在目标健康状态变化时输出一行带时间戳的汇总 (按最近 20 次结果评估)")
+	coalesce := flag.Bool("coalesce", false, "折叠同一目标连续出现的相同错误，错误变化或恢复时输出重复次数 (如 x37)")
+	onlyErrors := flag.Bool("only-errors", false, "只输出失败的结果 (附带时间戳)，统计仍包含全部结果")
+	weighted := flag.Bool("weighted", false, "目标格式为 target:weight，每轮按权重随机选择一个目标而不是全部 ping")
+	timeoutAsFailure := flag.Bool("timeout-as-failure", true, "超时是否计入丢包率和健康状态 (=false 时超时单独统计，用于区分 \"慢\" 和 \"宕机\")")
+	maxSamples := flag.Int("max-samples", 1000, "每个目标保留的最近结果数 (用于 SLA 窗口和状态文件)，汇总统计不受影响，0 表示全部保留")
+	circuitFailures := flag.Int("circuit-failures", 0, "目标连续失败 N 次后熔断，只按 -circuit-interval 慢速复查，成功后恢复 (0 表示不启用)")
+	circuitInterval := flag.Duration("circuit-interval", 30*time.Second, "熔断目标的复查间隔")
+	stagger := flag.Duration("stagger", 0, "首轮探测在该时间窗口内随机错开各目标 (压测模式下错开各 worker 的首个请求)，避免启动时的请求洪峰")
+	shuffle := flag.Bool("shuffle", false, "每轮随机打乱多个目标的探测顺序，消除固定顺序带来的时间偏差 (对 -bench 并发模式无效)")
+	seed := flag.Uint64("seed", 0, "-shuffle 使用的随机种子，相同种子得到相同顺序 (0 表示随机生成并打印)")
+	table := flag.Bool("table", false, "多目标时每轮以对齐的表格输出")
+	trace := flag.Bool("trace", false, "MTR 式路由追踪: 逐跳统计延迟和丢包 (需要 root 权限，忽略 -type，-continuous 时每轮刷新)")
+	bench := flag.Bool("bench", false, "压测模式: 以 -concurrency 个并发共发起 -n 次请求，输出吞吐量、延迟百分位和错误分类")
+	benchTotal := flag.Int("n", 100, "压测模式的请求总数")
+	benchConcurrency := flag.Int("concurrency", 10, "压测模式的并发数")
+	baselinePath := flag.String("baseline", "", "运行结束后与基线文件对比平均延迟，有目标退化时退出码为 1")
+	compareTo := flag.String("compare-to", "", "与 -baseline 相同，但还对响应时间样本做 Mann-Whitney U 检验，只有差异显著时才算退化 (基线需由 -save-baseline 保存样本)")
+	confidence := flag.Float64("confidence", 0.95, "-compare-to 显著性检验的置信水平 (0-1)")
+	saveBaseline := flag.String("save-baseline", "", "运行结束时把各目标的平均延迟保存为基线文件")
+	regressionThreshold := flag.Float64("regression-threshold", 10, "平均延迟超过基线多少百分比视为退化")
+	configPath := flag.String("config", "", "YAML 配置文件，定义额外的目标及其 SLA 告警规则 (max_latency, max_loss, cert_expiry)")
+	profile := flag.String("profile", "", "只运行 -config 中指定 profile 的目标 (如 prod)")
+	utc := flag.Bool("utc", false, "所有时间戳使用 UTC (等同于 -tz UTC)")
+	tz := flag.String("tz", "", "时间戳使用的 IANA 时区，如 Asia/Shanghai (默认本地时区)")
+	port := flag.Int("port", 0, "目标未写端口时使用的端口 (默认: https/quic 为 443，smtp 为 25，imap 为 143，pop3 为 110，redis 为 6379，memcached 为 11211，mysql 为 3306，postgres 为 5432，wss 为 443，http/tcp/ws 为 80，icmp 回退为 TCP 时为 80)")
+	//测试
+	flag.Parse()
+	unitStyle.human, unitStyle.si = *human || *si, *si
+
+	var replay *recording
+	if *replayPath != "" {
+		var err error
+		if replay, err = loadRecording(*replayPath); err != nil {
+			fmt.Printf(ColorRed+"错误: 读取录制文件失败: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+		if *target != "" || *configPath != "" || *allIPs || *srv || *weighted || *trace || *bench {
+			fmt.Println(ColorRed + "错误: -replay 不能与 -t、-config、-all-ips、-srv、-weighted、-trace 或 -bench 同时使用" + ColorReset)
+			os.Exit(1)
+		}
+		*pingType = replay.header.PingType
+	}
+
+	if *target == "" && *configPath == "" && replay == nil {
+		fmt.Println(ColorRed + "错误: 必须指定目标地址 -t" + ColorReset)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := setResolver(*resolver); err != nil {
+		fmt.Printf(ColorRed+"错误: 无效的 -resolver: %v\n"+ColorReset, err)
+		os.Exit(1)
+	}
+	if err := setTimezone(*utc, *tz); err != nil {
+		fmt.Printf(ColorRed+"错误: %v\n"+ColorReset, err)
+		os.Exit(1)
+	}
+
+	var err error
+	opts := &Options{
+		PingType: *pingType,
+		Timeout:  time.Duration(*timeout) * time.Second,
+		Verbose:  *verbose,
+		Resolve:  resolve,
+
+		NoRedirectOK:  *noRedirectOK,
+		ExpectJSON:    expectJSON,
+		ExpectHeader:  expectHeader,
+		Pins:          pins,
+		DumpChain:     *dumpChain,
+		KeepAlive:     *keepAlive,
+		VerifyBody:    *verifyBody,
+		ProbeBoth:     *probeBoth,
+		ConnectOnly:   *connectOnly,
+		RetryUnsafe:   *retryUnsafe,
+		StartTLS:      *startTLS,
+		RedisUser:     *redisUser,
+		RedisPassword: *redisPassword,
+		PostgresUser:  *postgresUser,
+		MaxSkew:       *maxSkew,
+
+		Method:      strings.ToUpper(*method),
+		BodyFile:    *bodyFile,
+		ContentType: *contentType,
+
+		ICMPSize: *icmpSize,
+		Linger:   *linger,
+	}
+	if *port != 0 {
+		if *port < 0 || *port > 65535 {
+			fmt.Printf(ColorRed+"错误: 无效的端口 %d\n"+ColorReset, *port)
+			os.Exit(1)
+		}
+		opts.Port = strconv.Itoa(*port)
+	}
+	if opts.Method == "" {
+		opts.Method = http.MethodGet
+		if opts.BodyFile != "" {
+			opts.Method = http.MethodPost
+		}
+	}
+	if *retries > 0 && !opts.retrySafe(PingResult{BytesSent: 1}) {
+		fmt.Fprintf(os.Stderr, ColorYellow+"注意: %s 不是幂等方法，请求发出后失败不会重试 (使用 -retry-unsafe 强制重试)\n"+ColorReset, opts.Method)
+	}
+	for _, f := range []struct {
+		path string
+		dst  *[]byte
+	}{{*sendFile, &opts.SendData}, {*expectFile, &opts.ExpectData}} {
+		if f.path == "" {
+			continue
+		}
+		if *f.dst, err = readPayloadFile(f.path); err != nil {
+			fmt.Printf(ColorRed+"错误: 读取载荷文件失败: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+	}
+	if opts.BodyFile != "" {
+		if _, err := os.Stat(opts.BodyFile); err != nil {
+			fmt.Printf(ColorRed+"错误: 无法读取 -body-file: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+	}
+	if *icmpSize < 0 || *icmpSize > 65500 {
+		fmt.Printf(ColorRed+"错误: 无效的 -size: %d\n"+ColorReset, *icmpSize)
+		os.Exit(1)
+	}
+	opts.ICMPPattern, err = parsePattern(*icmpPattern)
+	if err != nil {
+		fmt.Printf(ColorRed+"错误: 无效的 -pattern: %v\n"+ColorReset, err)
+		os.Exit(1)
+	}
+	if *check != "" {
+		expr, err := compileCheck(*check)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: 无效的 -check 表达式: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+		opts.Check = expr
+	}
+
+	if *checkScript != "" {
+		opts.Script, err = loadCheckScript(*checkScript)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: 无效的 -check-script: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+	}
+
+	if *hostFile != "" {
+		opts.Hosts, err = loadHostsFile(*hostFile)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: 读取 -hostfile 失败: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+	}
+
+	if *sshSpec != "" {
+		client, err := dialSSH(sshTunnelConfig{
+			Spec:     *sshSpec,
+			KeyFile:  *sshKey,
+			Insecure: *sshInsecure,
+			Timeout:  opts.Timeout,
+		})
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: SSH 连接失败: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+		defer client.Close()
+		opts.SSH = client
+	}
+
+	if *strictDNS {
+		opts.StrictDNS = true
+		opts.DNSServer = dnsServer(*dnsServerAddr)
+	}
+
+	if *proxyList != "" {
+		switch {
+		case *sshSpec != "":
+			fmt.Println(ColorRed + "错误: -proxy-list 不能与 -ssh 同时使用" + ColorReset)
+			os.Exit(1)
+		case slices.Contains([]string{"udp", "icmp", "quic"}, strings.ToLower(*pingType)):
+			fmt.Printf(ColorRed+"错误: -proxy-list 只支持基于 TCP 的 ping 类型，不支持 %s\n"+ColorReset, *pingType)
+			os.Exit(1)
+		}
+		if opts.Proxies, err = loadProxyList(*proxyList); err != nil {
+			fmt.Printf(ColorRed+"错误: 无法读取代理列表: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+	}
+
+	if opts.Family, err = parseFamily(*resolveFamily); err != nil {
+		fmt.Printf(ColorRed+"错误: %v\n"+ColorReset, err)
+		os.Exit(1)
+	}
+	if opts.Family != "" && (*sshSpec != "" || *proxyList != "") {
+		fmt.Println(ColorRed + "错误: -resolve-family 不能与 -ssh 或 -proxy-list 同时使用 (域名由跳板机或代理解析)" + ColorReset)
+		os.Exit(1)
+	}
+	if opts.Family == "dual" && (*allIPs || *resolveOnce) {
+		fmt.Println(ColorRed + "错误: -resolve-family dual 不能与 -all-ips 或 -resolve-once 同时使用 (固定地址后没有可竞速的地址)" + ColorReset)
+		os.Exit(1)
+	}
+
+	if _, ok := sinkFormats[*output]; !ok && *output != "text" {
+		fmt.Printf(ColorRed+"错误: 不支持的输出格式: %s\n"+ColorReset, *output)
+		os.Exit(1)
+	}
+
+	if opts.TLSVerify, err = parseTLSVerify(*tlsVerifyMode); err != nil {
+		fmt.Printf(ColorRed+"错误: 无效的 -verify: %v\n"+ColorReset, err)
+		os.Exit(1)
+	}
+	if *caCert != "" && *caCertAppend != "" {
+		fmt.Println(ColorRed + "错误: -cacert 和 -cacert-append 不能同时使用" + ColorReset)
+		os.Exit(1)
+	}
+	if *caCert != "" || *caCertAppend != "" {
+		path := *caCert
+		if path == "" {
+			path = *caCertAppend
+		}
+		if opts.TLSVerify.roots, err = loadRootCAs(path, *caCertAppend != ""); err != nil {
+			fmt.Printf(ColorRed+"错误: 无法加载 CA 证书: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+	}
+	if opts.ReadBody, err = parseReadBody(*readBody); err != nil {
+		fmt.Printf(ColorRed+"错误: 无效的 -read-body: %v\n"+ColorReset, err)
+		os.Exit(1)
+	}
+	var download *downloadCap
+	if limit, err := parseBytes(*maxDownload); err != nil {
+		fmt.Printf(ColorRed+"错误: 无效的 -max-download: %v\n"+ColorReset, err)
+		os.Exit(1)
+	} else if limit > 0 {
+		download = &downloadCap{limit: limit}
+	}
+	if opts.ProbeOrder, err = parseProbeOrder(*probeOrder); err != nil {
+		fmt.Printf(ColorRed+"错误: 无效的 -probe-order: %v\n"+ColorReset, err)
+		os.Exit(1)
+	}
+	if opts.ProbeOrder != nil && (!isHTTPType(*pingType) || *probeBoth || *connectOnly) {
+		fmt.Println(ColorRed + "错误: -probe-order 只适用于 -type http/https，且不能与 -probe-both 或 -connect-only 同时使用" + ColorReset)
+		os.Exit(1)
+	}
+	if opts.ReadLimit, err = parseBytes(*readLimit); err != nil {
+		fmt.Printf(ColorRed+"错误: 无效的 -read-limit: %v\n"+ColorReset, err)
+		os.Exit(1)
+	}
+	if opts.TraceHeader, err = parseTraceHeader(*traceHeader); err != nil {
+		fmt.Printf(ColorRed+"错误: 无效的 -trace-header: %v\n"+ColorReset, err)
+		os.Exit(1)
+	}
+	if opts.IfModifiedSince, err = parseIfModifiedSince(*ifModifiedSince); err != nil {
+		fmt.Printf(ColorRed+"错误: 无效的 -if-modified-since: %v\n"+ColorReset, err)
+		os.Exit(1)
+	}
+	opts.IfNoneMatch = *ifNoneMatch
+	if opts.ExpectStatus, err = parseStatusList(*expectStatus); err != nil {
+		fmt.Printf(ColorRed+"错误: 无效的 -expect-status: %v\n"+ColorReset, err)
+		os.Exit(1)
+	}
+
+	failTmpl, err := parseFailTemplate(*failTemplate)
+	if err != nil {
+		fmt.Printf(ColorRed+"错误: 无效的 -fail-template: %v\n"+ColorReset, err)
+		os.Exit(1)
+	}
+
+	abortStatuses, err := parseStatusList(*abortOnStatus)
+	if err != nil {
+		fmt.Printf(ColorRed+"错误: 无效的 -abort-on-status: %v\n"+ColorReset, err)
+		os.Exit(1)
+	}
+
+	targets := splitTargets(*target)
+	if *srv {
+		targets, err = lookupSRVTargets(*target)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: 解析 SRV 记录失败: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+	}
+	if replay != nil {
+		targets = replay.header.Targets
+	}
+	names := make([]string, len(targets))
+	if replay != nil {
+		copy(names, replay.header.Names)
+	} else if *checkNames != "" {
+		list := strings.Split(*checkNames, ",")
+		if len(list) != len(targets) {
+			fmt.Printf(ColorRed+"错误: -name 有 %d 个名称，但有 %d 个目标\n"+ColorReset, len(list), len(targets))
+			os.Exit(1)
+		}
+		for i, name := range list {
+			names[i] = strings.TrimSpace(name)
+		}
+	}
+	var baseline *baselineFile
+	significance := 0.0
+	if *compareTo != "" {
+		if *baselinePath != "" {
+			fmt.Println(ColorRed + "错误: -baseline 和 -compare-to 只能指定一个" + ColorReset)
+			os.Exit(1)
+		}
+		if *confidence <= 0 || *confidence >= 1 {
+			fmt.Println(ColorRed + "错误: -confidence 必须在 0 和 1 之间" + ColorReset)
+			os.Exit(1)
+		}
+		*baselinePath, significance = *compareTo, *confidence
+	}
+	if *baselinePath != "" {
+		baseline, err = loadBaseline(*baselinePath)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: 读取基线文件失败: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+	}
+
+	if *profile != "" && *configPath == "" {
+		fmt.Println(ColorRed + "错误: -profile 需要与 -config 一起使用" + ColorReset)
+		os.Exit(1)
+	}
+	var slaRules map[string][]slaRule
+	if *configPath != "" {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: 读取配置文件失败: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+		cfgTargets, err := cfg.selectTargets(*profile)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+		slaRules = make(map[string][]slaRule)
+		for _, t := range cfgTargets {
+			for _, rule := range t.Rules {
+				if *maxSamples > 0 && rule.Window > *maxSamples {
+					fmt.Printf(ColorRed+"错误: 规则 %s 的 window 超过 -max-samples (%d)\n"+ColorReset, rule.Name, *maxSamples)
+					os.Exit(1)
+				}
+			}
+			// 同一目标配置了不同名称时作为不同的检查
+			label := checkLabel(t.Target, t.Name)
+			if !slices.Contains(labelsOf(targets, names), label) {
+				targets = append(targets, t.Target)
+				names = append(names, t.Name)
+			}
+			slaRules[label] = append(slaRules[label], t.Rules...)
+		}
+	}
+	if len(targets) == 0 {
+		fmt.Println(ColorRed + "错误: 没有可 ping 的目标" + ColorReset)
+		os.Exit(1)
+	}
+	var picker *weightedPicker
+	if *weighted {
+		weights := make([]int, len(targets))
+		for i, t := range targets {
+			targets[i], weights[i], err = splitWeight(t)
+			if err != nil {
+				fmt.Printf(ColorRed+"错误: %v\n"+ColorReset, err)
+				os.Exit(1)
+			}
+		}
+		picker = newWeightedPicker(weights)
+	}
+	labels := labelsOf(targets, names)
+	for i, label := range labels {
+		if slices.Index(labels, label) != i {
+			fmt.Printf(ColorRed+"错误: 检查名称 %s 重复\n"+ColorReset, label)
+			os.Exit(1)
+		}
+	}
+
+	pingCount := *count
+	if *continuous {
+		pingCount = -1 // 无限次
+	}
+
+	if *allIPs && (*trace || *bench || *weighted) {
+		fmt.Println(ColorRed + "错误: -all-ips 不能与 -trace、-bench 或 -weighted 同时使用" + ColorReset)
+		os.Exit(1)
+	}
+
+	if *resolveOnce {
+		if *allIPs || *sshSpec != "" {
+			fmt.Println(ColorRed + "错误: -resolve-once 不能与 -all-ips 或 -ssh 同时使用" + ColorReset)
+			os.Exit(1)
+		}
+		if err := pinResolved(targets, opts); err != nil {
+			fmt.Printf(ColorRed+"错误: -resolve-once %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+	}
+
+	if *hold > 0 {
+		if len(targets) != 1 || strings.ToLower(*pingType) != "tcp" || *trace || *bench || *allIPs || opts.Proxies != nil {
+			fmt.Println(ColorRed + "错误: -hold 只支持 -type tcp 的单个目标，且不能与 -trace、-bench、-all-ips 或 -proxy-list 同时使用" + ColorReset)
+			os.Exit(1)
+		}
+		if *interval <= 0 {
+			fmt.Println(ColorRed + "错误: -hold 需要正的 -i 作为保活写入间隔" + ColorReset)
+			os.Exit(1)
+		}
+		printHeader(targets[0], *pingType)
+		dropped, err := runHold(targets[0], opts, *hold, time.Duration(*interval)*time.Second)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: 连接失败: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+		if dropped {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *trace {
+		if len(targets) != 1 {
+			fmt.Println(ColorRed + "错误: -trace 只支持单个目标" + ColorReset)
+			os.Exit(1)
+		}
+		if err := runTrace(targets[0], opts, pingCount, time.Duration(*interval)*time.Second); err != nil {
+			fmt.Printf(ColorRed+"错误: 路由追踪失败: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *bench {
+		if *benchTotal <= 0 || *benchConcurrency <= 0 {
+			fmt.Println(ColorRed + "错误: -n 和 -concurrency 必须大于 0" + ColorReset)
+			os.Exit(1)
+		}
+		printHeader(strings.Join(targets, ", "), *pingType)
+		fmt.Printf("压测: 共 %d 次请求，并发 %d\n", *benchTotal, *benchConcurrency)
+		runBench(targets, opts, *benchTotal, *benchConcurrency, *stagger).print()
+		return
+	}
+
+	var deadline time.Time
+	if *runFor > 0 {
+		deadline = time.Now().Add(*runFor)
+		pingCount = -1 // 按时长结束
+	}
+
+	checkOpts := make([]*Options, len(targets))
+	for i := range checkOpts {
+		checkOpts[i] = opts
+	}
+	var ipChecks []ipCheck
+	if *allIPs {
+		ipChecks, err = expandAllIPs(targets, names, opts)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: -all-ips %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+		targets, names, checkOpts = nil, nil, nil
+		for _, c := range ipChecks {
+			targets = append(targets, c.target)
+			names = append(names, c.name)
+			checkOpts = append(checkOpts, c.opts)
+			if c.group != "" && slaRules != nil {
+				slaRules[c.name] = slaRules[c.group]
+			}
+		}
+		labels = labelsOf(targets, names)
+	}
+
+	stats := make([]*targetStats, len(targets))
+	for i := range stats {
+		stats[i] = newTargetStats(*maxSamples, *timeoutAsFailure)
+		stats[i].name = names[i]
+		if ipChecks != nil && ipChecks[i].ip != "" {
+			stats[i].family = ipFamily(ipChecks[i].ip)
+		}
+		if opts.Proxies != nil {
+			stats[i].proxies = opts.Proxies.labels()
+		}
+		if *anomalyFactor > 0 {
+			stats[i].anomaly = newAnomalyDetector(*anomalyFactor, *anomalyWarmup)
+		}
+	}
+	budget := &retryBudget{remaining: *retryBudgetSize}
+
+	var sinks multiSink
+	if *output == "text" {
+		// 折叠、健康状态和聚合都需要看到全部结果，-only-errors 由 textSink 在输出时过滤，不使用 errorsOnlySink
+		text := &textSink{verbose: opts.Verbose, table: *table, onlyErrors: *onlyErrors, timestamps: *onlyErrors, failTmpl: failTmpl}
+		if *retries > 0 {
+			text.budget = budget
+		}
+		if *coalesce {
+			text.coalesce = newCoalescer()
+		}
+		if *summaryOnChange {
+			text.onChange = newHealthTracker()
+		}
+		if *aggregateSize > 0 {
+			// 由聚合器自行过滤没有失败的批次
+			text.agg = newAggregator(*aggregateSize, *timeoutAsFailure)
+			text.agg.onlyErrors = *onlyErrors
+		}
+		sinks = append(sinks, text)
+	} else {
+		sink, err := openSink(*output, "-", tags, *onlyErrors)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: 无法创建输出: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, sink)
+	}
+	// -json-out、-csv 和 -statsd 是对应 -sink 的简写
+	var implied sinkFlag
+	if *jsonOut != "" {
+		implied = append(implied, sinkSpec{"json", *jsonOut})
+	}
+	if *csvOut != "" {
+		implied = append(implied, sinkSpec{"csv", *csvOut})
+	}
+	if *statsdAddr != "" {
+		if *statsdFormat != "statsd" && *statsdFormat != "dogstatsd" {
+			fmt.Printf(ColorRed+"错误: 无效的 -statsd-format: %s\n"+ColorReset, *statsdFormat)
+			os.Exit(1)
+		}
+		implied = append(implied, sinkSpec{*statsdFormat, *statsdAddr})
+	}
+	if *influxDest != "" {
+		sink, err := newInfluxSink(*influxDest, tags, influxAuth{org: *influxOrg, bucket: *influxBucket, token: *influxToken})
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: 无法创建输出 %s: %v\n"+ColorReset, *influxDest, err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, sink)
+	}
+	for _, spec := range append(implied, extraSinks...) {
+		sink, err := openSink(spec.format, spec.dest, tags, *onlyErrors)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: 无法创建输出 %s: %v\n"+ColorReset, spec.dest, err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, sink)
+	}
+	if *dumpSeries != "" {
+		f, err := createOutputFile(*dumpSeries)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: 无法创建输出文件: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+		sink, err := newSeriesSink(f, *seriesFormat)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: 无效的 -series-format: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, sink)
+	}
+	if *useSyslog || *syslogAddr != "" {
+		sink, err := newSyslogSink(*syslogAddr)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: 无法连接 syslog: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, wrapOnlyErrors(sink, *onlyErrors))
+	}
+	if *fifoPath != "" {
+		sink, err := newFIFOSink(*fifoPath, tags)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: 无法使用 FIFO: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, wrapOnlyErrors(sink, *onlyErrors))
+	}
+	if *bell || *notify {
+		if *notify {
+			if err := notifyCommand("", "").Err; err != nil {
+				fmt.Printf(ColorRed+"错误: 无法发送桌面通知: %v\n"+ColorReset, err)
+				os.Exit(1)
+			}
+		}
+		sinks = append(sinks, newAlertSink(*bell, *notify, *alertInterval))
+	}
+	if *onFailure != "" || *onRecovery != "" {
+		sinks = append(sinks, newHookSink(*onFailure, *onRecovery, *hookTimeout))
+	}
+	if *statusFilePath != "" {
+		sinks = append(sinks, &statusFileSink{path: *statusFilePath})
+	}
+	if *saveBaseline != "" {
+		sinks = append(sinks, &baselineSink{path: *saveBaseline})
+	}
+	if *recordPath != "" {
+		f, err := createOutputFile(*recordPath)
+		if err != nil {
+			fmt.Printf(ColorRed+"错误: 无法创建录制文件: %v\n"+ColorReset, err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, newRecordSink(f, *pingType, targets, names))
+	}
+	defer func() {
+		if err := sinks.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, ColorRed+"错误: 写入输出失败: %v\n"+ColorReset, err)
+		}
+	}()
+	reportOutput(sinks.WriteHeader(labels, *pingType))
+
+	var adaptiveIv *adaptiveInterval
+	if *adaptive {
+		if *intervalMin <= 0 || *intervalMax < *intervalMin {
+			fmt.Println(ColorRed + "错误: -interval-min 必须大于 0 且不大于 -interval-max" + ColorReset)
+			os.Exit(1)
+		}
+		adaptiveIv = newAdaptiveInterval(time.Duration(*interval)*time.Second, *intervalMin, *intervalMax)
+	}
 
-	if *target == "" {
-		fmt.Println(ColorRed + "错误: 必须指定目标地址 -t" + ColorReset)
-		flag.Usage()
-		os.Exit(1)
+	var shuffleRand *rand.Rand
+	if *shuffle {
+		if *seed == 0 {
+			*seed = uint64(time.Now().UnixNano())
+			fmt.Fprintf(os.Stderr, "打乱顺序的随机种子: %d (使用 -seed 复现)\n", *seed)
+		}
+		shuffleRand = rand.New(rand.NewPCG(*seed, *seed))
 	}
-	printHeader(*target, *pingType)
 
-	var results []PingResult
-	successCount := 0
-	totalTime := time.Duration(0)
+	var breaker *circuitBreaker
+	if *circuitFailures > 0 {
+		breaker = newCircuitBreaker(len(targets), *circuitFailures, *circuitInterval)
+	}
 
-	pingCount := *count
-	if *continuous {
-		pingCount = -1 // 无限次
+	var statsMu sync.Mutex
+	if *output == "text" {
+		summaryOnSignal(&statsMu, labels, stats)
 	}
+	pause := newPauseController()
+	stop := stopOnSignal()
 
+	totalFailures := 0
+	failureGateTripped := false
+	run := newRunReport(pingCount, *runFor, time.Duration(*interval)*time.Second, adaptiveIv != nil)
 	iteration := 0
+	if replay != nil {
+		iteration = replay.play(sinks, stats, &statsMu, stop, *replayInstant)
+	}
 	for {
-		if pingCount > 0 && iteration >= pingCount {
+		// 回放时结果全部来自录制文件，不再探测
+		if replay != nil || (pingCount > 0 && iteration >= pingCount) {
+			break
+		}
+		pause.wait(stop)
+		if stopped(stop) {
 			break
 		}
 
-		var result PingResult
-		switch strings.ToLower(*pingType) {
-		case "http", "https":
-			result = pingHTTP(*target, *pingType, time.Duration(*timeout)*time.Second)
-		case "tcp":
-			result = pingTCP(*target, time.Duration(*timeout)*time.Second)
-		case "icmp":
-			fmt.Println(ColorYellow + "注意: ICMP ping 需要 root 权限，改用 TCP 连接测试" + ColorReset)
-			result = pingTCP(*target, time.Duration(*timeout)*time.Second)
-		default:
-			fmt.Printf(ColorRed+"不支持的 ping 类型: %s\n"+ColorReset, *pingType)
-			os.Exit(1)
+		order := make([]int, len(targets))
+		for i := range order {
+			order[i] = i
+		}
+		switch {
+		case picker != nil:
+			order = []int{picker.pick()}
+		case shuffleRand != nil:
+			shuffleRand.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		}
+
+		var round []PingResult
+		abortReason := ""
+		var probed []int
+		var offsets []time.Duration
+		roundStart := time.Now()
+		if iteration == 0 && *stagger > 0 {
+			offsets = staggerOffsets(len(order), *stagger)
 		}
+		for k, i := range order {
+			if breaker != nil && !breaker.allow(i, time.Now()) {
+				continue
+			}
+			if offsets != nil && !sleepOrStop(time.Until(roundStart.Add(offsets[k])), stop) {
+				break
+			}
+			probed = append(probed, i)
+			t := targets[i]
+			result := ping(t, checkOpts[i])
+			for !result.Success && result.Retries < *retries && checkOpts[i].retrySafe(result) && budget.take() {
+				retried := result.Retries + 1
+				result = ping(t, checkOpts[i])
+				result.Retries = retried
+			}
+			result.Name = names[i]
 
-		results = append(results, result)
-		printResult(result, iteration+1)
+			statsMu.Lock()
+			if stats[i].anomaly != nil {
+				stats[i].anomaly.check(&result)
+			}
+			stats[i].add(result)
+			statsMu.Unlock()
+			round = append(round, result)
+			reportOutput(sinks.WriteResult(result, iteration+1))
+			if breaker != nil {
+				breaker.record(i, labels[i], result.Success, time.Now())
+			}
+
+			if abortStatuses[result.StatusCode] {
+				abortReason = fmt.Sprintf("收到状态码 %d，停止检查", result.StatusCode)
+				break
+			}
+			if download != nil && download.add(result.BytesRecv) {
+				abortReason = fmt.Sprintf("累计接收 %s，达到 -max-download 上限，停止检查", formatBytes(download.total))
+				break
+			}
+			if !result.Success {
+				totalFailures++
+				if *maxFailures > 0 && totalFailures >= *maxFailures {
+					failureGateTripped = true
+					abortReason = fmt.Sprintf("累计失败 %d 次，达到 -max-failures 上限，停止检查", totalFailures)
+					break
+				}
+			}
+		}
 
-		if result.Success {
-			successCount++
-			totalTime += result.ResponseTime
+		reportOutput(sinks.EndRound(round, iteration+1))
+		checkSLA(slaRules, labels, stats, probed)
+		if abortReason != "" {
+			fmt.Fprintln(os.Stderr, ColorYellow+abortReason+ColorReset)
+			run.reason = abortReason
+			break
 		}
 
 		iteration++
 
 		if pingCount < 0 || iteration < pingCount {
-			time.Sleep(time.Duration(*interval) * time.Second)
+			wait := time.Duration(*interval) * time.Second
+			if adaptiveIv != nil {
+				wait = adaptiveIv.next(round)
+			}
+			// 下一轮开始时已超过 -for 时长，不再等待
+			if !deadline.IsZero() && !time.Now().Add(wait).Before(deadline) {
+				break
+			}
+			if !sleepOrStop(wait, stop) {
+				break
+			}
+		}
+	}
+
+	reportOutput(sinks.WriteSummary(targets, stats))
+	if ipChecks != nil && *output == "text" {
+		printIPGroups(ipChecks, stats)
+	}
+	if opts.Proxies != nil && *output == "text" {
+		printProxyGroups(labels, stats)
+	}
+	if breaker != nil && *output == "text" {
+		breaker.printOpen(labels)
+	}
+	if *output == "text" {
+		run.finished = iteration
+		if run.reason == "" && stopped(stop) {
+			run.reason = "收到中断信号"
+		}
+		run.print()
+	}
+
+	exitCode := 0
+	if failureGateTripped {
+		exitCode = 1
+	}
+	if baseline != nil && compareBaseline(baseline, labels, stats, *regressionThreshold, significance) > 0 {
+		exitCode = 1
+	}
+	if exitCode != 0 {
+		reportOutput(sinks.Close())
+		os.Exit(exitCode)
+	}
+}
+
+// reportOutput 报告写入输出时发生的错误，不中断探测
+func reportOutput(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, ColorRed+"错误: 写入输出失败: %v\n"+ColorReset, err)
+	}
+}
+
+// checkLabel 返回输出中代表一个检查的名称: 指定了名称时使用名称，否则使用目标地址
+func checkLabel(target, name string) string {
+	if name != "" {
+		return name
+	}
+	return target
+}
+
+// labelsOf 按 checkLabel 计算每个检查的名称
+func labelsOf(targets, names []string) []string {
+	labels := make([]string, len(targets))
+	for i, t := range targets {
+		labels[i] = checkLabel(t, names[i])
+	}
+	return labels
+}
+
+// statsLabels 与 labelsOf 相同，名称取自各目标的统计
+func statsLabels(targets []string, stats []*targetStats) []string {
+	labels := make([]string, len(targets))
+	for i, t := range targets {
+		labels[i] = checkLabel(t, stats[i].name)
+	}
+	return labels
+}
+
+// label 返回结果所属检查的名称
+func (r PingResult) label() string { return checkLabel(r.Target, r.Name) }
+
+// splitTargets 拆分逗号分隔的目标列表并去除空项
+func splitTargets(s string) []string {
+	var targets []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			targets = append(targets, t)
 		}
 	}
+	return targets
+}
 
-	printSummary(results, successCount, totalTime)
+// parseStatusList 解析逗号分隔的 HTTP 状态码列表
+func parseStatusList(s string) (map[int]bool, error) {
+	statuses := make(map[int]bool)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		code, err := strconv.Atoi(field)
+		if err != nil || code < 100 || code > 999 {
+			return nil, fmt.Errorf("无效的状态码 %q", field)
+		}
+		statuses[code] = true
+	}
+	return statuses, nil
 }
 
 func printHeader(target, pingType string) {
 	fmt.Printf("\n%s=== 服务健康检查工具 ===%s\n", ColorCyan, ColorReset)
 	fmt.Printf("目标: %s\n", target)
 	fmt.Printf("类型: %s\n", strings.ToUpper(pingType))
-	fmt.Printf("时间: %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+	fmt.Printf("时间: %s\n\n", time.Now().Format("2006-01-02 15:04:05 MST"))
 }
 
-func pingHTTP(target, protocol string, timeout time.Duration) PingResult {
-	result := PingResult{Target: target}
+// setTimezone 按 -utc/-tz 替换 time.Local，之后所有输出和文件中的时间戳都使用该时区
+func setTimezone(utc bool, tz string) error {
+	if utc && tz != "" && tz != "UTC" {
+		return fmt.Errorf("-utc 与 -tz %s 不能同时使用", tz)
+	}
+	if utc {
+		tz = "UTC"
+	}
+	if tz == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return fmt.Errorf("无效的时区 %q: %w", tz, err)
+	}
+	time.Local = loc
+	return nil
+}
+
+// ping 执行一次探测并记录发起时间
+func ping(target string, opts *Options) PingResult {
+	proxy := ""
+	if opts.Proxies != nil {
+		proxy, opts = opts.Proxies.pick(opts, target)
+	}
+	start := time.Now()
+	var cnames []string
+	var dnsErr error
+	if opts.StrictDNS {
+		if host := strictDNSHost(target, opts); host != "" {
+			cnames, dnsErr = checkDNS(host, opts.DNSServer, opts.Timeout)
+		}
+	}
+	var result PingResult
+	if dnsErr != nil {
+		result = PingResult{Target: target, Error: dnsErr, ResponseTime: time.Since(start)}
+	} else {
+		result = probe(target, opts)
+	}
+	result.Proxy = proxy
+	result.CNAMEs = cnames
+	if opts.Family != "" {
+		result.Family = addrFamily(result.RemoteAddr)
+	}
+	if opts.Script != nil && !isHTTPType(opts.PingType) {
+		applyScript(opts.Script, checkEnv{result: result, pingType: opts.PingType, header: http.Header{}}, &result)
+	}
+	result.Timestamp = start
+	result.NearTimeout = result.ResponseTime >= time.Duration(float64(opts.Timeout)*nearTimeoutRatio)
+	return result
+}
+
+// isHTTPType 判断是否为 HTTP 探测，HTTP 的检查脚本在 pingHTTP 内求值以便访问响应头和响应体
+func isHTTPType(pingType string) bool {
+	t := strings.ToLower(pingType)
+	return t == "http" || t == "https"
+}
+
+// probe 按 ping 类型分发到具体的探测实现
+func probe(target string, opts *Options) PingResult {
+	switch strings.ToLower(opts.PingType) {
+	case "http", "https":
+		switch {
+		case opts.ProbeOrder != nil:
+			return pingStages(target, opts)
+		case opts.ConnectOnly:
+			return pingConnectOnly(target, opts)
+		case opts.ProbeBoth:
+			return pingBoth(target, opts)
+		}
+		return pingHTTP(target, opts)
+	case "tcp":
+		return pingTCP(target, opts)
+	case "udp":
+		return pingUDP(target, opts)
+	case "icmp":
+		return pingICMP(target, opts)
+	case "quic":
+		return pingQUIC(target, opts)
+	case "smtp", "imap", "pop3":
+		return pingMail(target, opts)
+	case "redis", "memcached":
+		return pingCache(target, opts)
+	case "mysql", "postgres":
+		return pingDB(target, opts)
+	case "ws", "wss":
+		return pingWebSocket(target, opts)
+	default:
+		fmt.Printf(ColorRed+"不支持的 ping 类型: %s\n"+ColorReset, opts.PingType)
+		os.Exit(1)
+	}
+	return PingResult{}
+}
+
+func pingHTTP(target string, opts *Options) (result PingResult) {
+	result.Target = target
+
+	// 检查脚本最后求值，能看到字节数等全部字段；请求失败时响应头和响应体为空
+	header, body := http.Header{}, []byte(nil)
+	if opts.Script != nil {
+		defer func() {
+			env := checkEnv{result: result, pingType: opts.PingType, header: header, body: string(body)}
+			applyScript(opts.Script, env, &result)
+		}()
+	}
+
+	url := httpURL(target, opts)
 
-	// 确保 URL 格式正确
-	url := target
-	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
-		url = protocol + "://" + target
+	transport, release := opts.httpTransport()
+
+	// 字节数按本次请求在连接上产生的增量统计；新建连接从 0 开始，包含 TLS 握手
+	var conn *countingConn
+	var baseSent, baseRecv int64
+	var start time.Time
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			result.ConnReused = info.Reused
+			result.RemoteAddr = info.Conn.RemoteAddr().String()
+			if conn = unwrapCountingConn(info.Conn); conn != nil && info.Reused {
+				baseSent, baseRecv = conn.sent.Load(), conn.received.Load()
+			}
+		},
+		GotFirstResponseByte: func() {
+			result.TTFB = time.Since(start)
+		},
 	}
+	defer func() {
+		release()
+		if conn != nil {
+			result.BytesSent = conn.sent.Load() - baseSent
+			result.BytesRecv = conn.received.Load() - baseRecv
+		}
+	}()
 
 	client := &http.Client{
-		Timeout: timeout,
+		Transport: transport,
+		Timeout:   opts.Timeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse // 不跟随重定向
 		},
 	}
 
-	start := time.Now()
-	resp, err := client.Get(url)
+	req, err := newHTTPRequest(url, opts)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.TraceID = opts.setTraceHeader(req)
+	if opts.Port != "" && req.URL.Port() == "" {
+		req.URL.Host = net.JoinHostPort(req.URL.Hostname(), opts.Port)
+		req.Host = req.URL.Host
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	start = time.Now()
+	resp, err := client.Do(req)
 	result.ResponseTime = time.Since(start)
+	if err == nil && opts.ReadBody == readBodyNone && result.TTFB > 0 {
+		result.ResponseTime = result.TTFB
+	}
 
 	if err != nil {
-		result.Error = err
+		if opts.DumpChain {
+			result.Chain = inspectChain(presentedChain(nil, err), opts.TLSVerify.roots, time.Now())
+		}
+		result.Error = explainReset(err)
 		return result
 	}
-	defer resp.Body.Close()
+	defer func() {
+		if opts.KeepAlive {
+			// 读完响应体连接才能放回连接池复用
+			io.Copy(io.Discard, io.LimitReader(resp.Body, maxKeepAliveDrain))
+		}
+		resp.Body.Close()
+	}()
 
 	result.StatusCode = resp.StatusCode
-	result.Success = resp.StatusCode < 500 // 状态码 < 500 视为成功
+	header = resp.Header
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		result.CertNotAfter = resp.TLS.PeerCertificates[0].NotAfter
+		result.TLSReport = opts.TLSVerify.report(resp.TLS, req.URL.Hostname())
+		if opts.DumpChain {
+			result.Chain = inspectChain(resp.TLS.PeerCertificates, opts.TLSVerify.roots, time.Now())
+		}
+	}
+	if serverTime, ok := parseServerTiming(resp.Header); ok {
+		result.ServerTime = serverTime
+	}
+	if skew, ok := clockSkew(resp.Header, start, result.ResponseTime); ok {
+		result.ClockSkew = skew
+	}
+	if len(opts.Pins) > 0 {
+		if err := opts.Pins.check(resp.TLS); err != nil {
+			result.Error = err
+			return result
+		}
+	}
+	if len(opts.ExpectStatus) > 0 {
+		if err := opts.checkExpectStatus(resp.StatusCode); err != nil {
+			result.Error = err
+			return result
+		}
+	}
+	if err := opts.checkSkew(result.ClockSkew); err != nil {
+		result.Error = err
+		return result
+	}
+	result.Success = resp.StatusCode < 500 || opts.ExpectStatus[resp.StatusCode] // 状态码 < 500 视为成功
+	if opts.NoRedirectOK && !opts.ExpectStatus[resp.StatusCode] && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		result.Success = false
+		result.Error = fmt.Errorf("收到重定向 %d -> %s", resp.StatusCode, resp.Header.Get("Location"))
+		return result
+	}
+
+	for _, a := range opts.ExpectHeader {
+		if err := a.check(resp.Header); err != nil {
+			result.Success = false
+			result.Error = err
+			return result
+		}
+	}
+
+	result.Encoding = resp.Header.Get("Content-Encoding")
+	var wire *countingReader
+	var reader io.Reader = resp.Body
+	if opts.VerifyBody || opts.needBody() {
+		// 断言针对解码后的内容，压缩前后的大小分别记录
+		if reader, wire, err = decodeBody(resp); err != nil {
+			result.Success = false
+			result.Error = err
+			return result
+		}
+		defer func() { result.WireBytes = wire.n }()
+	}
+
+	switch {
+	case opts.VerifyBody:
+		result.Chunked = slices.Contains(resp.TransferEncoding, "chunked")
+		body, result.BodyBytes, err = readFullBody(reader)
+		if err != nil {
+			result.Success = false
+			result.Error = fmt.Errorf("响应体不完整 (已读取 %d 字节): %w", result.BodyBytes, explainReset(err))
+			return result
+		}
+		// trailer 只有在读到响应体末尾后才会填充
+		result.Trailer = resp.Trailer
+	case opts.needBody():
+		body, err = io.ReadAll(io.LimitReader(reader, maxCheckBody))
+		if err != nil {
+			result.Success = false
+			result.Error = fmt.Errorf("读取响应体失败: %w", explainReset(err))
+			return result
+		}
+	}
+	if opts.ReadBody == readBodyFull && !opts.VerifyBody {
+		var read int64
+		if wire != nil {
+			read = wire.n
+		}
+		n, err := opts.drainBody(resp, read)
+		if wire != nil {
+			wire.n += n
+		} else {
+			result.WireBytes = n
+		}
+		if err != nil {
+			result.Success = false
+			result.Error = fmt.Errorf("读取响应体失败: %w", explainReset(err))
+			return result
+		}
+	}
+	if body != nil || result.BodyBytes > 0 || opts.ReadBody == readBodyFull {
+		result.TotalTime = time.Since(start)
+	}
+	if opts.ReadBody == readBodyFull {
+		result.ResponseTime = result.TotalTime
+	}
+
+	if len(opts.ExpectJSON) > 0 {
+		if err := checkJSONAssertions(body, opts.ExpectJSON); err != nil {
+			result.Success = false
+			result.Error = err
+			return result
+		}
+	}
+
+	if opts.Check != nil {
+		env := checkEnv{result: result, pingType: opts.PingType, header: resp.Header, body: string(body)}
+		ok, err := opts.Check.Eval(env)
+		result.Success = ok
+		switch {
+		case err != nil:
+			result.Error = fmt.Errorf("检查表达式求值失败: %v", err)
+		case !ok:
+			result.Error = fmt.Errorf("检查未通过: %s", opts.Check.source)
+		}
+	}
 
 	return result
 }
 
-func pingTCP(target string, timeout time.Duration) PingResult {
-	result := PingResult{Target: target}
+// withDefaultPort 在地址缺少端口时补上 port，支持 host、host:port、IPv6、[IPv6]
+// 和 [IPv6]:port 几种写法。不能简单地判断是否包含冒号: IPv6 地址 (包括
+// fe80::1%eth0 这类带区域标识的链路本地地址) 本身就含有冒号
+func withDefaultPort(target, port string) string {
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return target
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(target, "["), "]")
+	return net.JoinHostPort(host, port)
+}
+
+// httpURL 确保 URL 格式正确，未写协议时使用 -type 指定的协议
+func httpURL(target string, opts *Options) string {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		return target
+	}
+	return strings.ToLower(opts.PingType) + "://" + urlHost(target)
+}
+
+// urlHost 把裸 IPv6 地址转换为 URL 中的 [addr] 形式，区域标识中的 % 需要转义为 %25
+func urlHost(target string) string {
+	addr, zone, _ := strings.Cut(target, "%")
+	if ip := net.ParseIP(addr); ip == nil || ip.To4() != nil {
+		return target
+	}
+	if zone != "" {
+		addr += "%25" + zone
+	}
+	return "[" + addr + "]"
+}
+
+// newHTTPRequest 构造 HTTP 请求；请求体来自 -body-file 时直接以文件作为 Body 流式发送
+func newHTTPRequest(url string, opts *Options) (*http.Request, error) {
+	if opts.BodyFile == "" {
+		req, err := http.NewRequest(opts.Method, url, nil)
+		if err == nil {
+			opts.setRequestHeaders(req)
+		}
+		return req, err
+	}
+
+	f, err := os.Open(opts.BodyFile)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
 
-	// 如果没有端口，默认使用 80
-	if !strings.Contains(target, ":") {
-		target += ":80"
+	req, err := http.NewRequest(opts.Method, url, f)
+	if err != nil {
+		f.Close()
+		return nil, err
 	}
+	opts.setRequestHeaders(req)
+	req.ContentLength = info.Size()
+	if opts.ContentType != "" {
+		req.Header.Set("Content-Type", opts.ContentType)
+	}
+	return req, nil
+}
+
+func pingTCP(target string, opts *Options) PingResult {
+	result := PingResult{Target: target}
+
+	// 如果没有端口，按 ping 类型或 -port 补上默认端口
+	target = withDefaultPort(target, opts.defaultPort())
 
 	start := time.Now()
-	conn, err := net.DialTimeout("tcp", target, timeout)
+	conn, err := opts.dial("tcp", target)
 	result.ResponseTime = time.Since(start)
 
 	if err != nil {
-		result.Error = err
+		result.Error = explainPortExhaustion(err)
 		return result
 	}
 	defer conn.Close()
+	result.RemoteAddr = conn.RemoteAddr().String()
+	if tc, ok := conn.(*net.TCPConn); ok && opts.Linger >= 0 {
+		tc.SetLinger(opts.Linger)
+	}
+
+	if opts.SendData != nil || opts.ExpectData != nil {
+		result.BytesSent, result.BytesRecv, err = exchangePayload(conn, opts, false)
+		result.ResponseTime = time.Since(start)
+		if err != nil {
+			result.Error = err
+			return result
+		}
+	}
 
 	result.Success = true
 	return result
 }
 
-func printResult(result PingResult, seq int) {
+// printResult 打印单次结果；failTmpl 非 nil 时失败消息完全由 -fail-template 生成 (不加序号和颜色)
+func printResult(result PingResult, seq int, failTmpl *template.Template) {
+	if !result.Success && failTmpl != nil {
+		fmt.Println(renderFailTemplate(failTmpl, result, seq))
+		return
+	}
+
 	prefix := fmt.Sprintf("[%d]", seq)
+	if result.Retries > 0 {
+		prefix += fmt.Sprintf(" (重试 %d 次)", result.Retries)
+	}
+	if result.NearTimeout {
+		prefix += ColorYellow + " (接近超时)" + ColorReset
+	}
+	if result.Anomaly {
+		prefix += ColorYellow + " (延迟异常)" + ColorReset
+	}
 
 	if result.Success {
+		connect := ""
+		if result.ConnectTime > 0 {
+			connect = fmt.Sprintf(" 连接=%v", formatDuration(result.ConnectTime, time.Millisecond))
+		}
 		if result.StatusCode > 0 {
-			fmt.Printf("%s %s响应来自 %s: 状态=%d 时间=%v%s\n",
-				prefix, ColorGreen, result.Target, result.StatusCode,
-				result.ResponseTime.Round(time.Millisecond), ColorReset)
+			fmt.Printf("%s %s响应来自 %s: 状态=%d%s 时间=%v%s\n",
+				prefix, ColorGreen, result.label(), result.StatusCode, connect,
+				formatDuration(result.ResponseTime, time.Millisecond), ColorReset)
 		} else {
-			fmt.Printf("%s %s响应来自 %s: 连接成功 时间=%v%s\n",
-				prefix, ColorGreen, result.Target,
-				result.ResponseTime.Round(time.Millisecond), ColorReset)
+			fmt.Printf("%s %s响应来自 %s: 连接成功%s 时间=%v%s\n",
+				prefix, ColorGreen, result.label(), connect,
+				formatDuration(result.ResponseTime, time.Millisecond), ColorReset)
 		}
 	} else {
-		fmt.Printf("%s %s请求失败 %s: %v%s\n",
-			prefix, ColorRed, result.Target, result.Error, ColorReset)
+		fmt.Printf("%s %s请求失败 %s: %s%v%s\n",
+			prefix, ColorRed, result.label(), errorTag(result.Error), result.Error, ColorReset)
+	}
+}
+
+// printVerbose 在 -v 模式下打印单次结果的附加细节
+func printVerbose(result PingResult) {
+	if result.TraceID != "" {
+		fmt.Printf("    关联 ID: %s\n", result.TraceID)
+	}
+	if result.Anomaly {
+		fmt.Printf("    延迟异常: 超过阈值 %v (本次运行 p%.0f × -anomaly-factor)\n",
+			formatDuration(result.AnomalyThreshold, time.Millisecond), anomalyQuantile*100)
+	}
+	if result.RemoteAddr != "" {
+		fmt.Printf("    远端地址: %s\n", result.RemoteAddr)
+	}
+	if result.Family != "" {
+		fmt.Printf("    协议族: %s\n", familyLabel(result.Family))
+	}
+	if result.Proxy != "" {
+		fmt.Printf("    代理: %s\n", result.Proxy)
+	}
+	if len(result.CNAMEs) > 0 {
+		fmt.Printf("    CNAME: %s\n", strings.Join(result.CNAMEs, " → "))
+	}
+	if result.Banner != "" {
+		fmt.Printf("    服务器标识: %s\n", result.Banner)
+	}
+	if result.StatusCode > 0 {
+		if result.ConnReused {
+			fmt.Println("    连接: 复用")
+		} else {
+			fmt.Println("    连接: 新建")
+		}
+	}
+	if result.TLSReport != "" {
+		fmt.Printf("    TLS 校验: %s\n", result.TLSReport)
+	}
+	if result.Chain != nil {
+		printChain(result.Chain)
+	}
+	if len(result.Stages) > 0 {
+		parts := make([]string, len(result.Stages))
+		for i, st := range result.Stages {
+			parts[i] = fmt.Sprintf("%s %v", st.Name, formatDuration(st.Duration, time.Millisecond))
+			if st.Error != "" {
+				parts[i] += " (失败)"
+			}
+		}
+		fmt.Printf("    阶段: %s\n", strings.Join(parts, " → "))
+	}
+	if result.QUICVersion != "" {
+		resumed, early := "新会话", "未使用"
+		if result.Resumed {
+			resumed = "会话恢复"
+		}
+		if result.Used0RTT {
+			early = "已使用"
+		}
+		fmt.Printf("    QUIC: 版本 %s，ALPN %s，%s，0-RTT %s\n", result.QUICVersion, cmp.Or(result.ALPN, "-"), resumed, early)
+	}
+	if result.StatusCode == http.StatusNotModified {
+		fmt.Println("    缓存验证: 304 Not Modified，服务器确认缓存仍然有效")
+	}
+	if result.Encoding != "" && result.WireBytes > 0 {
+		fmt.Printf("    内容编码: %s，传输 %s (解码前)\n", result.Encoding, formatBytes(result.WireBytes))
+	}
+	if result.BodyBytes > 0 || result.Trailer != nil {
+		encoding := ""
+		if result.Chunked {
+			encoding = " (chunked)"
+		}
+		fmt.Printf("    响应体: %s%s，传输完整\n", formatBytes(result.BodyBytes), encoding)
+		for name, values := range result.Trailer {
+			fmt.Printf("    Trailer: %s: %s\n", name, strings.Join(values, ", "))
+		}
+	}
+	if result.TTFB > 0 {
+		if result.TotalTime > 0 {
+			fmt.Printf("    首字节(TTFB)=%v 完整下载=%v (传输 %v)\n", formatDuration(result.TTFB, time.Microsecond),
+				formatDuration(result.TotalTime, time.Microsecond), formatDuration(result.TotalTime-result.TTFB, time.Microsecond))
+		} else {
+			fmt.Printf("    首字节(TTFB)=%v (未读取响应体，使用 -read-body full 测量完整下载时间)\n", formatDuration(result.TTFB, time.Microsecond))
+		}
+	}
+	if result.ClockSkew != 0 {
+		fmt.Printf("    时钟偏差: 服务器比本机%s (Date 头，误差约 ±0.5s)\n", formatSkew(result.ClockSkew))
+	}
+	if result.ServerTime > 0 {
+		network := result.ResponseTime - result.ServerTime
+		if network < 0 {
+			network = 0
+		}
+		fmt.Printf("    总耗时=%v 服务端处理=%v 网络延迟(估算)=%v\n",
+			formatDuration(result.ResponseTime, time.Millisecond),
+			formatDuration(result.ServerTime, time.Millisecond),
+			formatDuration(network, time.Millisecond))
 	}
 }
 
-func printSummary(results []PingResult, successCount int, totalTime time.Duration) {
-	fmt.Printf("\n%s=== 统计信息 ===%s\n", ColorCyan, ColorReset)
+// printSummary 打印单个目标的统计信息，target 非空时在标题中标注目标
+func printSummary(target string, stats *targetStats) {
+	sum := stats.summary()
+
+	if target != "" {
+		fmt.Printf("\n%s=== 统计信息: %s ===%s\n", ColorCyan, target, ColorReset)
+	} else {
+		fmt.Printf("\n%s=== 统计信息 ===%s\n", ColorCyan, ColorReset)
+	}
+	if sum.Sent == 0 {
+		fmt.Printf("发送: 0\n\n")
+		return
+	}
 	fmt.Printf("发送: %d, 成功: %d, 失败: %d (%.1f%% 丢包)\n",
-		len(results), successCount, len(results)-successCount,
-		float64(len(results)-successCount)/float64(len(results))*100)
-
-	if successCount > 0 {
-		avgTime := totalTime / time.Duration(successCount)
-		fmt.Printf("平均响应时间: %v\n", avgTime.Round(time.Millisecond))
-
-		// 计算最小和最大响应时间
-		var minTime, maxTime time.Duration
-		first := true
-		for _, r := range results {
-			if r.Success {
-				if first {
-					minTime = r.ResponseTime
-					maxTime = r.ResponseTime
-					first = false
-				} else {
-					if r.ResponseTime < minTime {
-						minTime = r.ResponseTime
-					}
-					if r.ResponseTime > maxTime {
-						maxTime = r.ResponseTime
-					}
-				}
+		sum.Sent, sum.Success, sum.Failed, sum.LossPercent)
+	if sum.Timeouts > 0 || !stats.timeoutAsFailure {
+		counted := "计入丢包率"
+		if !stats.timeoutAsFailure {
+			counted = "不计入丢包率"
+		}
+		fmt.Printf("超时: %d 次 (%s)\n", sum.Timeouts, counted)
+	}
+	if len(sum.DNSFailures) > 0 {
+		var parts []string
+		for _, c := range dnsCategories {
+			if n := sum.DNSFailures[c.key]; n > 0 {
+				parts = append(parts, fmt.Sprintf("%s %d 次", c.label, n))
 			}
 		}
+		fmt.Printf("DNS 失败: %s\n", strings.Join(parts, "，"))
+	}
+
+	if sum.Success > 0 {
+		fmt.Printf("平均响应时间: %v\n", formatDuration(sum.Avg, time.Millisecond))
 		fmt.Printf("最小/最大响应时间: %v / %v\n",
-			minTime.Round(time.Millisecond), maxTime.Round(time.Millisecond))
+			formatDuration(sum.Min, time.Millisecond), formatDuration(sum.Max, time.Millisecond))
+	}
+	if sum.Success > 1 {
+		fmt.Printf("响应时间标准差: %v\n", formatDuration(sum.StdDev, time.Millisecond))
+		fmt.Printf("响应时间分位数: p50=%v p90=%v p99=%v\n", formatDuration(sum.P50, time.Millisecond),
+			formatDuration(sum.P90, time.Millisecond), formatDuration(sum.P99, time.Millisecond))
 	}
 
-	// 健康状态评估
-	successRate := float64(successCount) / float64(len(results)) * 100
-	var status, color string
-	switch {
-	case successRate == 100:
-		status = "优秀"
-		color = ColorGreen
-	case successRate >= 90:
-		status = "良好"
-		color = ColorGreen
-	case successRate >= 70:
-		status = "一般"
-		color = ColorYellow
-	default:
-		status = "较差"
-		color = ColorRed
+	if sum.BytesSent > 0 || sum.BytesRecv > 0 {
+		fmt.Printf("传输: 发送 %s, 接收 %s\n", formatBytes(sum.BytesSent), formatBytes(sum.BytesRecv))
 	}
-	fmt.Printf("\n服务健康状态: %s%s%s\n\n", color, status, ColorReset)
-}
\ No newline at end of file
+	if sum.ConnReused > 0 {
+		fmt.Printf("连接复用: %d/%d (%.1f%%)\n", sum.ConnReused, sum.Sent, float64(sum.ConnReused)/float64(sum.Sent)*100)
+	}
+	if sum.Retries > 0 {
+		fmt.Printf("重试: %d 次\n", sum.Retries)
+	}
+	if stats.anomaly != nil || sum.Anomalies > 0 {
+		fmt.Printf("延迟异常: %d 次 (超过本次运行 p%.0f 的 -anomaly-factor 倍)\n", sum.Anomalies, anomalyQuantile*100)
+	}
+	if sum.Corrupted > 0 {
+		fmt.Printf("%s数据损坏: %d 次 (%.1f%%，收到回复但回显数据不一致，不计入丢包)%s\n", ColorYellow, sum.Corrupted, sum.CorruptRate, ColorReset)
+	}
+
+	if sum.Sent > 0 && float64(sum.NearTimeout)/float64(sum.Sent) >= nearTimeoutWarnShare {
+		fmt.Printf("%s警告: %d 个样本的响应时间接近超时上限 (≥%.0f%%)，统计可能被超时截断，建议调大 -timeout%s\n",
+			ColorYellow, sum.NearTimeout, nearTimeoutRatio*100, ColorReset)
+	}
+
+	health := healthOf(sum.SuccessRate())
+	fmt.Printf("\n服务健康状态: %s%s%s\n\n", health.color, health.label, ColorReset)
+}
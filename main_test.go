@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestWithDefaultPort(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"192.0.2.1", "192.0.2.1:80"},
+		{"192.0.2.1:8080", "192.0.2.1:8080"},
+		{"::1", "[::1]:80"},
+		{"2001:db8::1", "[2001:db8::1]:80"},
+		{"[::1]", "[::1]:80"},
+		{"[2001:db8::1]", "[2001:db8::1]:80"},
+		{"[::1]:80", "[::1]:80"},
+		{"[2001:db8::1]:8080", "[2001:db8::1]:8080"},
+		{"example.com", "example.com:80"},
+		{"example.com:8080", "example.com:8080"},
+	}
+	for _, tt := range tests {
+		if got := withDefaultPort(tt.target, "80"); got != tt.want {
+			t.Errorf("withDefaultPort(%q, \"80\") = %q, 期望 %q", tt.target, got, tt.want)
+		}
+	}
+}
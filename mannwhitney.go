@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math"
+	"slices"
+)
+
+// mannWhitneyMinSamples 是做显著性检验时每组至少需要的样本数，更少时正态近似不可靠
+const mannWhitneyMinSamples = 8
+
+// mannWhitneyU 对两组样本做 Mann-Whitney U 检验，返回双侧 p 值。
+// 检验不假设延迟服从正态分布，对长尾和离群值不敏感。使用带结点校正和连续性校正的正态近似。
+func mannWhitneyU(a, b []float64) float64 {
+	type sample struct {
+		value float64
+		first bool // 属于 a
+	}
+	all := make([]sample, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, sample{v, true})
+	}
+	for _, v := range b {
+		all = append(all, sample{v, false})
+	}
+	slices.SortFunc(all, func(x, y sample) int {
+		switch {
+		case x.value < y.value:
+			return -1
+		case x.value > y.value:
+			return 1
+		}
+		return 0
+	})
+
+	// 相同的值取平均秩，同时累计结点校正项 Σ(t³-t)
+	var rankA, ties float64
+	for i := 0; i < len(all); {
+		j := i + 1
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // 第 i+1 到第 j 名的平均
+		for k := i; k < j; k++ {
+			if all[k].first {
+				rankA += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	n1, n2 := float64(len(a)), float64(len(b))
+	n := n1 + n2
+	u := rankA - n1*(n1+1)/2
+	mean := n1 * n2 / 2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		return 1 // 所有样本都相同
+	}
+	z := (math.Abs(u-mean) - 0.5) / sigma
+	if z < 0 {
+		z = 0
+	}
+	return math.Erfc(z / math.Sqrt2)
+}
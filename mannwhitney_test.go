@@ -0,0 +1,57 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{
+			// Hollander & Wolfe 的例子 (R 文档 wilcox.test 中的 x, y)，U = 35
+			name: "教科书样本",
+			a:    []float64{0.80, 0.83, 1.89, 1.04, 1.45, 1.38, 1.91, 1.64, 0.73, 1.46},
+			b:    []float64{1.15, 0.88, 0.90, 0.74, 1.21},
+			want: 0.244624,
+		},
+		{
+			// 两组完全分开，U = 0，z = (32-0.5)/sqrt(8*8*17/12)
+			name: "完全分离",
+			a:    []float64{1, 2, 3, 4, 5, 6, 7, 8},
+			b:    []float64{9, 10, 11, 12, 13, 14, 15, 16},
+			want: 0.000939106,
+		},
+		{
+			// 秩: 1, 2.5×2, 5.5×4, 9×3, 12×3, 14.5×2, 16，U = 7.5，Σ(t³-t) = 120
+			name: "含结点",
+			a:    []float64{1, 2, 2, 3, 3, 3, 4, 5},
+			b:    []float64{3, 4, 4, 5, 5, 6, 6, 7},
+			want: 0.0105152,
+		},
+		{
+			name: "两组相同",
+			a:    []float64{1, 2, 3, 4, 5, 6, 7, 8},
+			b:    []float64{8, 7, 6, 5, 4, 3, 2, 1},
+			want: 1,
+		},
+		{
+			name: "所有样本相同",
+			a:    []float64{5, 5, 5, 5, 5, 5, 5, 5},
+			b:    []float64{5, 5, 5, 5, 5, 5, 5, 5},
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		if got := mannWhitneyU(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: p = %.7f, 期望 %.7f", tt.name, got, tt.want)
+		}
+		// 双侧检验与两组的先后顺序无关
+		if got, back := mannWhitneyU(tt.a, tt.b), mannWhitneyU(tt.b, tt.a); math.Abs(got-back) > 1e-12 {
+			t.Errorf("%s: 交换两组后 p 由 %v 变为 %v", tt.name, got, back)
+		}
+	}
+}
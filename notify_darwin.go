@@ -0,0 +1,15 @@
+//go:build darwin
+
+package main
+
+import "os/exec"
+
+// notifyCommand 通过 osascript 的 display notification 发送通知中心通知。
+// 标题和内容作为 argv 传入脚本，不拼接进 AppleScript 源码，无需转义。
+func notifyCommand(title, message string) *exec.Cmd {
+	return exec.Command("osascript",
+		"-e", "on run argv",
+		"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+		"-e", "end run",
+		title, message)
+}
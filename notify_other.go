@@ -0,0 +1,10 @@
+//go:build !darwin && !windows
+
+package main
+
+import "os/exec"
+
+// notifyCommand 通过 notify-send (libnotify) 发送桌面通知
+func notifyCommand(title, message string) *exec.Cmd {
+	return exec.Command("notify-send", "--app-name=ping-tool", title, message)
+}
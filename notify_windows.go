@@ -0,0 +1,24 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// toastScript 用 Windows.UI.Notifications 显示一条两行 toast，文本从环境变量读取
+const toastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$t = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$x = $t.GetElementsByTagName('text')
+$x.Item(0).AppendChild($t.CreateTextNode($env:PING_TOOL_TITLE)) > $null
+$x.Item(1).AppendChild($t.CreateTextNode($env:PING_TOOL_MESSAGE)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('ping-tool').Show([Windows.UI.Notifications.ToastNotification]::new($t))`
+
+// notifyCommand 通过 PowerShell 发送 Windows toast 通知。
+// 标题和内容经环境变量传入，不拼接进脚本，无需转义。
+func notifyCommand(title, message string) *exec.Cmd {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.Env = append(os.Environ(), "PING_TOOL_TITLE="+title, "PING_TOOL_MESSAGE="+message)
+	return cmd
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// openMetricsBuckets 是延迟直方图的桶上限 (秒)，与 Prometheus 客户端库的默认值一致
+var openMetricsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// exemplar 是直方图桶中最近一次落入该桶的探测，便于从指标跳转到具体的那次请求
+type exemplar struct {
+	seq     int
+	traceID string  // -trace-header 的关联 ID，可直接在链路追踪系统中查找
+	value   float64 // 秒
+	ts      time.Time
+}
+
+// latencyHistogram 累计一个检查的成功响应时间，counts 比 openMetricsBuckets 多一个 +Inf 桶 (非累计)
+type latencyHistogram struct {
+	counts    []int
+	exemplars []*exemplar
+	sum       float64
+	count     int
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	n := len(openMetricsBuckets) + 1
+	return &latencyHistogram{counts: make([]int, n), exemplars: make([]*exemplar, n)}
+}
+
+func (h *latencyHistogram) observe(result PingResult, seq int) {
+	v := result.ResponseTime.Seconds()
+	i := len(openMetricsBuckets)
+	for j, le := range openMetricsBuckets {
+		if v <= le {
+			i = j
+			break
+		}
+	}
+	h.counts[i]++
+	h.exemplars[i] = &exemplar{seq: seq, traceID: result.TraceID, value: v, ts: result.Timestamp}
+	h.sum += v
+	h.count++
+}
+
+// openMetricsSink 实现 -o openmetrics: 运行结束时输出一份 OpenMetrics 文本格式的指标，
+// 包含计数器、最后状态和带 exemplar 的延迟直方图，以 # EOF 结尾。
+// 直方图由逐条结果累计，因此不能被 -only-errors 过滤。
+type openMetricsSink struct {
+	f     *os.File
+	tags  tagFlag
+	hists map[string]*latencyHistogram // 检查名称 -> 直方图
+}
+
+func init() {
+	registerSink("openmetrics", sinkFormat{
+		help:       "运行结束时输出 OpenMetrics 指标",
+		allResults: true,
+		create:     fileSink(func(f *os.File, tags tagFlag) OutputSink { return newOpenMetricsSink(f, tags) }),
+	})
+}
+
+func newOpenMetricsSink(f *os.File, tags tagFlag) *openMetricsSink {
+	return &openMetricsSink{f: f, tags: tags, hists: make(map[string]*latencyHistogram)}
+}
+
+func (s *openMetricsSink) WriteHeader([]string, string) error { return nil }
+
+func (s *openMetricsSink) WriteResult(result PingResult, seq int) error {
+	if !result.Success {
+		return nil
+	}
+	h := s.hists[result.label()]
+	if h == nil {
+		h = newLatencyHistogram()
+		s.hists[result.label()] = h
+	}
+	h.observe(result, seq)
+	return nil
+}
+
+func (s *openMetricsSink) EndRound([]PingResult, int) error { return nil }
+
+// openMetricsFamily 是一个指标族的元数据和全部样本行
+type openMetricsFamily struct {
+	name, typ, unit, help string
+	lines                 []string
+}
+
+func (s *openMetricsSink) WriteSummary(targets []string, stats []*targetStats) error {
+	probes := &openMetricsFamily{name: "ping_probes", typ: "counter", help: "探测次数"}
+	failures := &openMetricsFamily{name: "ping_probe_failures", typ: "counter", help: "失败的探测次数"}
+	timeouts := &openMetricsFamily{name: "ping_probe_timeouts", typ: "counter", help: "超时的探测次数"}
+	up := &openMetricsFamily{name: "ping_up", typ: "gauge", help: "最近一次探测是否成功 (1 成功，0 失败)"}
+	loss := &openMetricsFamily{name: "ping_loss_ratio", typ: "gauge", unit: "ratio", help: "丢包率 (0-1)"}
+	latency := &openMetricsFamily{name: "ping_latency_seconds", typ: "histogram", unit: "seconds", help: "成功探测的响应时间"}
+
+	for i, t := range targets {
+		labels := s.labels(t, stats[i].name)
+		sum := stats[i].summary()
+		probes.add("_total", labels, strconv.Itoa(sum.Sent))
+		failures.add("_total", labels, strconv.Itoa(sum.Failed))
+		timeouts.add("_total", labels, strconv.Itoa(sum.Timeouts))
+		upValue := "0"
+		if last, ok := stats[i].last(); ok && last.Success {
+			upValue = "1"
+		}
+		up.add("", labels, upValue)
+		loss.add("", labels, formatOpenMetricsFloat(sum.LossPercent/100))
+
+		h := s.hists[checkLabel(t, stats[i].name)]
+		if h == nil {
+			h = newLatencyHistogram()
+		}
+		cumulative := 0
+		for j, n := range h.counts {
+			cumulative += n
+			le := "+Inf"
+			if j < len(openMetricsBuckets) {
+				le = formatOpenMetricsFloat(openMetricsBuckets[j])
+			}
+			line := fmt.Sprintf("%s_bucket%s %d", latency.name, labels.with("le", le), cumulative)
+			if e := h.exemplars[j]; e != nil {
+				exLabels := openMetricsLabels{}.with("seq", strconv.Itoa(e.seq))
+				if e.traceID != "" {
+					exLabels = exLabels.with("trace_id", e.traceID)
+				}
+				line += fmt.Sprintf(" # %s %s %s", exLabels, formatOpenMetricsFloat(e.value), formatOpenMetricsTime(e.ts))
+			}
+			latency.lines = append(latency.lines, line)
+		}
+		latency.add("_sum", labels, formatOpenMetricsFloat(h.sum))
+		latency.add("_count", labels, strconv.Itoa(h.count))
+	}
+
+	var b strings.Builder
+	for _, fam := range []*openMetricsFamily{probes, failures, timeouts, up, loss, latency} {
+		fmt.Fprintf(&b, "# TYPE %s %s\n", fam.name, fam.typ)
+		if fam.unit != "" {
+			fmt.Fprintf(&b, "# UNIT %s %s\n", fam.name, fam.unit)
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n", fam.name, fam.help)
+		for _, line := range fam.lines {
+			b.WriteString(line + "\n")
+		}
+	}
+	b.WriteString("# EOF\n")
+	_, err := s.f.WriteString(b.String())
+	return err
+}
+
+func (s *openMetricsSink) Close() error { return closeOutputFile(s.f) }
+
+func (f *openMetricsFamily) add(suffix string, labels openMetricsLabels, value string) {
+	f.lines = append(f.lines, f.name+suffix+labels.String()+" "+value)
+}
+
+// openMetricsLabels 是按顺序输出的标签，值已转义
+type openMetricsLabels []string
+
+// labels 返回一个检查的标签: target、可选的 name 和 -tag 标签
+func (s *openMetricsSink) labels(target, name string) openMetricsLabels {
+	var l openMetricsLabels
+	l = l.with("target", target)
+	if name != "" {
+		l = l.with("name", name)
+	}
+	for _, t := range s.tags {
+		l = l.with(t.key, t.value)
+	}
+	return l
+}
+
+func (l openMetricsLabels) with(key, value string) openMetricsLabels {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return append(l[:len(l):len(l)], key+`="`+escaped+`"`)
+}
+
+func (l openMetricsLabels) String() string {
+	return "{" + strings.Join(l, ",") + "}"
+}
+
+// formatOpenMetricsFloat 以最短形式输出浮点数，如 0.005、1
+func formatOpenMetricsFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// formatOpenMetricsTime 以 Unix 秒 (带小数) 输出 exemplar 的时间戳
+func formatOpenMetricsTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', 3, 64)
+}
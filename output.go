@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// OutputSink 是结果输出目标。同一次运行可以挂载多个 sink，
+// 例如终端文本输出的同时把 JSON 和 CSV 写入文件。
+type OutputSink interface {
+	// WriteHeader 在第一次探测前调用
+	WriteHeader(targets []string, pingType string) error
+	// WriteResult 在每次探测完成后调用。写到标准输出的 sink 应在返回前写出该结果，
+	// 不在内部缓冲，通过管道 (如 tee) 消费的下游才能实时看到每条结果
+	WriteResult(result PingResult, seq int) error
+	// EndRound 在一轮所有目标探测完成后调用
+	EndRound(round []PingResult, seq int) error
+	// WriteSummary 在运行结束时调用，stats 与 targets 一一对应
+	WriteSummary(targets []string, stats []*targetStats) error
+	// Close 刷新并释放 sink 持有的资源
+	Close() error
+}
+
+// sinkFormat 是一种可由 -o 或 -sink 选择的输出格式。dest 是输出目标: 文件路径
+// ("-" 表示标准输出)，或网络后端的地址
+type sinkFormat struct {
+	help       string // -o 帮助中的说明
+	allResults bool   // 需要看到全部结果 (如累计直方图)，不受 -only-errors 过滤
+	create     func(dest string, tags tagFlag) (OutputSink, error)
+}
+
+// sinkFormats 是已注册的输出格式，内置格式在各自的文件中通过 init 注册
+var sinkFormats = make(map[string]sinkFormat)
+
+// registerSink 注册一种输出格式。新的监控后端只需在自己的文件中实现 OutputSink 并调用它，
+// 不必修改主流程
+func registerSink(name string, format sinkFormat) {
+	if _, dup := sinkFormats[name]; dup {
+		panic("重复注册的输出格式: " + name)
+	}
+	sinkFormats[name] = format
+}
+
+// fileSink 把写入 *os.File 的 sink 构造函数适配为 sinkFormat.create
+func fileSink(create func(*os.File, tagFlag) OutputSink) func(string, tagFlag) (OutputSink, error) {
+	return func(dest string, tags tagFlag) (OutputSink, error) {
+		f, err := createOutputFile(dest)
+		if err != nil {
+			return nil, err
+		}
+		return create(f, tags), nil
+	}
+}
+
+// openSink 按名称创建 sink，并在启用 -only-errors 时加上失败过滤
+func openSink(name, dest string, tags tagFlag, onlyErrors bool) (OutputSink, error) {
+	format, ok := sinkFormats[name]
+	if !ok {
+		return nil, fmt.Errorf("未知的输出格式 %q，可选: %s", name, strings.Join(slices.Sorted(maps.Keys(sinkFormats)), ", "))
+	}
+	sink, err := format.create(dest, tags)
+	if err != nil {
+		return nil, err
+	}
+	if format.allResults {
+		return sink, nil
+	}
+	return wrapOnlyErrors(sink, onlyErrors), nil
+}
+
+// sinkFormatsHelp 返回 -o 帮助中已注册格式的列表
+func sinkFormatsHelp() string {
+	parts := []string{"text"}
+	for _, name := range slices.Sorted(maps.Keys(sinkFormats)) {
+		if help := sinkFormats[name].help; help != "" {
+			name += " (" + help + ")"
+		}
+		parts = append(parts, name)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sinkSpec 是一个 -sink format=dest 参数
+type sinkSpec struct {
+	format, dest string
+}
+
+// sinkFlag 实现可重复的 -sink format=dest 参数，格式在解析参数之后才校验
+type sinkFlag []sinkSpec
+
+func (f *sinkFlag) String() string {
+	parts := make([]string, len(*f))
+	for i, s := range *f {
+		parts[i] = s.format + "=" + s.dest
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f *sinkFlag) Set(s string) error {
+	format, dest, ok := strings.Cut(s, "=")
+	if !ok || format == "" || dest == "" {
+		return errors.New("格式应为 format=dest，如 json=results.ndjson")
+	}
+	*f = append(*f, sinkSpec{strings.TrimSpace(format), dest})
+	return nil
+}
+
+// multiSink 把每次调用分发给所有 sink，并合并返回的错误
+type multiSink []OutputSink
+
+func (m multiSink) WriteHeader(targets []string, pingType string) error {
+	var errs []error
+	for _, s := range m {
+		errs = append(errs, s.WriteHeader(targets, pingType))
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiSink) WriteResult(result PingResult, seq int) error {
+	var errs []error
+	for _, s := range m {
+		errs = append(errs, s.WriteResult(result, seq))
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiSink) EndRound(round []PingResult, seq int) error {
+	var errs []error
+	for _, s := range m {
+		errs = append(errs, s.EndRound(round, seq))
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiSink) WriteSummary(targets []string, stats []*targetStats) error {
+	var errs []error
+	for _, s := range m {
+		errs = append(errs, s.WriteSummary(targets, stats))
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiSink) Close() error {
+	var errs []error
+	for _, s := range m {
+		errs = append(errs, s.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// errorsOnlySink 实现 -only-errors: 只把失败的结果转发给下层 sink，统计不受影响
+type errorsOnlySink struct {
+	OutputSink
+}
+
+func (e errorsOnlySink) WriteResult(result PingResult, seq int) error {
+	if result.Success {
+		return nil
+	}
+	return e.OutputSink.WriteResult(result, seq)
+}
+
+func (e errorsOnlySink) EndRound(round []PingResult, seq int) error {
+	var failed []PingResult
+	for _, r := range round {
+		if !r.Success {
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return e.OutputSink.EndRound(failed, seq)
+}
+
+// wrapOnlyErrors 在启用 -only-errors 时为 sink 加上失败过滤
+func wrapOnlyErrors(sink OutputSink, enabled bool) OutputSink {
+	if !enabled {
+		return sink
+	}
+	return errorsOnlySink{sink}
+}
+
+// createOutputFile 创建输出文件，path 为 "-" 时使用标准输出
+func createOutputFile(path string) (*os.File, error) {
+	if path == "-" {
+		return os.Stdout, nil
+	}
+	return os.Create(path)
+}
+
+// closeOutputFile 关闭输出文件，标准输出不关闭
+func closeOutputFile(f *os.File) error {
+	if f == os.Stdout {
+		return nil
+	}
+	return f.Close()
+}
+
+// textSink 是默认的终端彩色文本输出
+type textSink struct {
+	verbose    bool
+	table      bool
+	onlyErrors bool // -only-errors: 只输出失败的结果，折叠和健康状态仍基于全部结果
+	timestamps bool // 在每条结果前显示时间
+	agg        *aggregator
+	coalesce   *coalescer         // 非 nil 时折叠连续相同的错误
+	onChange   *healthTracker     // 非 nil 时只在健康状态变化时输出
+	budget     *retryBudget       // 非 nil 时在统计末尾显示剩余重试预算
+	failTmpl   *template.Template // -fail-template，非 nil 时用于失败消息
+}
+
+func (t *textSink) WriteHeader(targets []string, pingType string) error {
+	printHeader(strings.Join(targets, ", "), pingType)
+	return nil
+}
+
+func (t *textSink) WriteResult(result PingResult, seq int) error {
+	switch {
+	case t.onChange != nil:
+		t.onChange.add(result)
+	case t.agg != nil:
+		t.agg.add(result)
+	case !t.table:
+		if t.coalesce != nil && t.coalesce.suppress(result, seq, t.timestamps) {
+			return nil
+		}
+		if t.onlyErrors && result.Success {
+			return nil
+		}
+		if t.timestamps {
+			fmt.Print(result.Timestamp.Format("2006-01-02 15:04:05") + " ")
+		}
+		printResult(result, seq, t.failTmpl)
+		if t.verbose {
+			printVerbose(result)
+		}
+	}
+	return nil
+}
+
+func (t *textSink) EndRound(round []PingResult, seq int) error {
+	if t.onChange != nil {
+		t.onChange.endRound(round)
+		return nil
+	}
+	if t.table && t.agg == nil {
+		if t.onlyErrors {
+			round = slices.DeleteFunc(slices.Clone(round), func(r PingResult) bool { return r.Success })
+			if len(round) == 0 {
+				return nil
+			}
+		}
+		printRoundTable(round, seq)
+	}
+	return nil
+}
+
+func (t *textSink) WriteSummary(targets []string, stats []*targetStats) error {
+	labels := statsLabels(targets, stats)
+	if t.agg != nil {
+		t.agg.flush(labels)
+	}
+	if t.coalesce != nil {
+		t.coalesce.flush(labels, t.timestamps)
+	}
+	for i, label := range labels {
+		if len(labels) == 1 {
+			label = ""
+		}
+		printSummary(label, stats[i])
+	}
+	if t.budget != nil && t.budget.remaining >= 0 {
+		fmt.Printf("重试预算: 剩余 %d 次\n\n", t.budget.remaining)
+	}
+	return nil
+}
+
+func (t *textSink) Close() error { return nil }
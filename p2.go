@@ -0,0 +1,95 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+// p2Quantile 用 P² 算法 (Jain & Chlamtac, 1985) 流式估计分位数，
+// 只维护 5 个标记点，内存占用与样本数无关。
+type p2Quantile struct {
+	p     float64
+	count int
+	q     [5]float64 // 标记点高度
+	n     [5]int     // 标记点的实际位置 (从 1 开始)
+	np    [5]float64 // 标记点的期望位置
+	dn    [5]float64 // 每个新样本带来的期望位置增量
+}
+
+func newP2Quantile(p float64) *p2Quantile {
+	return &p2Quantile{p: p, dn: [5]float64{0, p / 2, p, (1 + p) / 2, 1}}
+}
+
+func (e *p2Quantile) add(d time.Duration) {
+	x := float64(d)
+	if e.count < 5 {
+		e.q[e.count] = x
+		e.count++
+		if e.count == 5 {
+			slices.Sort(e.q[:])
+			e.n = [5]int{1, 2, 3, 4, 5}
+			e.np = [5]float64{1, 1 + 2*e.p, 1 + 4*e.p, 3 + 2*e.p, 5}
+		}
+		return
+	}
+	e.count++
+
+	// 找到样本所在的区间，必要时扩展两端的极值
+	var k int
+	switch {
+	case x < e.q[0]:
+		e.q[0] = x
+		k = 0
+	case x >= e.q[4]:
+		e.q[4] = x
+		k = 3
+	default:
+		for k = 0; k < 3 && x >= e.q[k+1]; k++ {
+		}
+	}
+	for i := k + 1; i < 5; i++ {
+		e.n[i]++
+	}
+	for i := range e.np {
+		e.np[i] += e.dn[i]
+	}
+
+	// 调整中间三个标记点，优先使用抛物线插值，结果越界时退回线性插值
+	for i := 1; i <= 3; i++ {
+		d := e.np[i] - float64(e.n[i])
+		if (d >= 1 && e.n[i+1]-e.n[i] > 1) || (d <= -1 && e.n[i-1]-e.n[i] < -1) {
+			s := 1
+			if d < 0 {
+				s = -1
+			}
+			if q := e.parabolic(i, s); e.q[i-1] < q && q < e.q[i+1] {
+				e.q[i] = q
+			} else {
+				e.q[i] += float64(s) * (e.q[i+s] - e.q[i]) / float64(e.n[i+s]-e.n[i])
+			}
+			e.n[i] += s
+		}
+	}
+}
+
+func (e *p2Quantile) parabolic(i, s int) float64 {
+	d := float64(s)
+	n0, n1, n2 := float64(e.n[i-1]), float64(e.n[i]), float64(e.n[i+1])
+	return e.q[i] + d/(n2-n0)*((n1-n0+d)*(e.q[i+1]-e.q[i])/(n2-n1)+(n2-n1-d)*(e.q[i]-e.q[i-1])/(n1-n0))
+}
+
+// value 返回当前的分位数估计；样本不足 5 个时直接按最近秩法计算
+func (e *p2Quantile) value() time.Duration {
+	if e.count == 0 {
+		return 0
+	}
+	if e.count < 5 {
+		sorted := make([]time.Duration, e.count)
+		for i, v := range e.q[:e.count] {
+			sorted[i] = time.Duration(v)
+		}
+		slices.Sort(sorted)
+		return percentile(sorted, e.p*100)
+	}
+	return time.Duration(e.q[2])
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+// exactQuantile 按排序后的样本线性插值计算分位数
+func exactQuantile(sorted []time.Duration, p float64) time.Duration {
+	pos := p * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	frac := pos - float64(i)
+	return sorted[i] + time.Duration(frac*float64(sorted[i+1]-sorted[i]))
+}
+
+func within(t *testing.T, name string, got, want time.Duration, tolerance float64) {
+	t.Helper()
+	if diff := math.Abs(float64(got - want)); diff > tolerance*float64(want) {
+		t.Errorf("%s = %v, 精确值 %v, 偏差超过 %.1f%%", name, got, want, tolerance*100)
+	}
+}
+
+// TestStreamingStats 将流式统计 (Welford 均值和标准差、P² 分位数) 与对全部样本排序得到的精确值比较。
+// 样本数超过 -max-samples 时缓冲区只保留最近的结果，流式统计仍应覆盖全部样本
+func TestStreamingStats(t *testing.T) {
+	for _, maxSamples := range []int{0, 100} {
+		rng := rand.New(rand.NewPCG(1, 2))
+		const n = 5000
+		stats := newTargetStats(maxSamples, true)
+		p95 := newP2Quantile(0.95)
+		samples := make([]time.Duration, n)
+		for i := range samples {
+			// 对数正态分布，接近真实延迟的长尾形状 (中位数约 50ms)
+			d := time.Duration(math.Exp(rng.NormFloat64()*0.5) * float64(50*time.Millisecond))
+			samples[i] = d
+			stats.add(PingResult{Success: true, ResponseTime: d})
+			p95.add(d)
+		}
+
+		var total float64
+		for _, d := range samples {
+			total += float64(d)
+		}
+		mean := total / n
+		var sq float64
+		for _, d := range samples {
+			sq += (float64(d) - mean) * (float64(d) - mean)
+		}
+		stddev := math.Sqrt(sq / (n - 1))
+		sorted := slices.Clone(samples)
+		slices.Sort(sorted)
+
+		sum := stats.summary()
+		if sum.Sent != n || sum.Success != n {
+			t.Fatalf("max-samples=%d: 发送 %d 成功 %d, 期望均为 %d", maxSamples, sum.Sent, sum.Success, n)
+		}
+		if maxSamples > 0 && len(stats.samples(0)) != maxSamples {
+			t.Errorf("max-samples=%d: 缓冲区保留了 %d 个结果", maxSamples, len(stats.samples(0)))
+		}
+		within(t, "平均值", sum.Avg, time.Duration(mean), 1e-6)
+		within(t, "标准差", sum.StdDev, time.Duration(stddev), 1e-6)
+		if sum.Min != sorted[0] || sum.Max != sorted[n-1] {
+			t.Errorf("最小/最大值 = %v/%v, 期望 %v/%v", sum.Min, sum.Max, sorted[0], sorted[n-1])
+		}
+		within(t, "p50", sum.P50, exactQuantile(sorted, 0.5), 0.02)
+		within(t, "p90", sum.P90, exactQuantile(sorted, 0.9), 0.03)
+		within(t, "p95", p95.value(), exactQuantile(sorted, 0.95), 0.03)
+		within(t, "p99", sum.P99, exactQuantile(sorted, 0.99), 0.05)
+	}
+}
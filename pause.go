@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"time"
+)
+
+// pauseController 通过信号切换暂停/恢复状态，暂停期间不发送探测也不计入次数，
+// 已累计的统计数据保持不变。
+type pauseController struct {
+	paused atomic.Bool
+}
+
+// newPauseController 开始监听暂停信号；当前平台不支持时返回的控制器永远不会暂停
+func newPauseController() *pauseController {
+	pc := &pauseController{}
+	if len(pauseSignals) == 0 {
+		return pc
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, pauseSignals...)
+	go func() {
+		for range ch {
+			if pc.paused.Load() {
+				pc.paused.Store(false)
+				fmt.Fprintf(os.Stderr, "%s[%s] 已恢复%s\n", ColorYellow, time.Now().Format("15:04:05"), ColorReset)
+			} else {
+				pc.paused.Store(true)
+				fmt.Fprintf(os.Stderr, "%s[%s] 已暂停，再次发送信号以恢复%s\n", ColorYellow, time.Now().Format("15:04:05"), ColorReset)
+			}
+		}
+	}()
+	return pc
+}
+
+// wait 在暂停期间阻塞，收到停止信号时立即返回
+func (pc *pauseController) wait(stop <-chan struct{}) {
+	for pc.paused.Load() && sleepOrStop(100*time.Millisecond, stop) {
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// maxPayloadFile 是 -send-file 和 -expect-file 允许的最大文件大小
+const maxPayloadFile = 64 << 10
+
+// readPayloadFile 读取载荷文件，超过 maxPayloadFile 时报错
+func readPayloadFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxPayloadFile+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPayloadFile {
+		return nil, fmt.Errorf("%s 超过 %s 上限", path, formatBytes(maxPayloadFile))
+	}
+	return data, nil
+}
+
+// exchangePayload 在已建立的连接上发送 -send-file 的内容并读取回复。
+// 设置了 -expect-file 时回复开头必须与之一致；datagram 为 true (UDP) 时
+// 读取一个数据报，且即使没有 -expect-file 也必须收到回复才算成功。
+func exchangePayload(conn net.Conn, opts *Options, datagram bool) (sent, recv int64, err error) {
+	if err := conn.SetDeadline(time.Now().Add(opts.Timeout)); err != nil {
+		return 0, 0, err
+	}
+
+	n, err := conn.Write(opts.SendData)
+	sent = int64(n)
+	if err != nil {
+		return sent, 0, explainReset(err)
+	}
+
+	var reply []byte
+	switch {
+	case datagram:
+		buf := make([]byte, 65536)
+		n, err = conn.Read(buf)
+		reply = buf[:n]
+	case len(opts.ExpectData) > 0:
+		buf := make([]byte, len(opts.ExpectData))
+		n, err = io.ReadFull(conn, buf)
+		reply = buf[:n]
+	default:
+		return sent, 0, nil // TCP 只发送不校验回复
+	}
+	recv = int64(n)
+	if err != nil && !(errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)) {
+		return sent, recv, explainReset(err)
+	}
+	if err != nil && !datagram && bytes.HasPrefix(opts.ExpectData, reply) {
+		// 已收到的部分都正确，只是连接提前关闭，与内容不一致区分开
+		return sent, recv, fmt.Errorf("连接在回复完成前关闭 (收到 %d/%d 字节): %w", len(reply), len(opts.ExpectData), io.ErrUnexpectedEOF)
+	}
+	if !bytes.HasPrefix(reply, opts.ExpectData) {
+		return sent, recv, fmt.Errorf("回复内容与 -expect-file 不一致 (收到 %d 字节)", len(reply))
+	}
+	return sent, recv, nil
+}
+
+// pingUDP 向目标发送一个数据报并等待回复。UDP 没有连接，只有收到回复才能确认服务可用。
+func pingUDP(target string, opts *Options) PingResult {
+	result := PingResult{Target: target}
+	if _, _, err := net.SplitHostPort(target); err != nil && opts.Port == "" {
+		result.Error = errors.New("UDP 目标必须指定端口 (host:port 或 -port)")
+		return result
+	}
+	target = withDefaultPort(target, opts.Port)
+
+	start := time.Now()
+	conn, err := opts.dial("udp", target)
+	if err != nil {
+		result.ResponseTime = time.Since(start)
+		result.Error = err
+		return result
+	}
+	defer conn.Close()
+	result.RemoteAddr = conn.RemoteAddr().String()
+
+	result.BytesSent, result.BytesRecv, err = exchangePayload(conn, opts, true)
+	result.ResponseTime = time.Since(start)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.Success = true
+	return result
+}
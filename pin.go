@@ -0,0 +1,53 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// pinFlag 实现可重复的 -pin-sha256 参数，每个值是证书公钥 (SPKI) SHA-256 的 base64 编码，
+// 与 HPKP 和 curl --pinnedpubkey 的格式相同
+type pinFlag []string
+
+func (f *pinFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *pinFlag) Set(s string) error {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "sha256//")
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(raw) != sha256.Size {
+		return fmt.Errorf("无效的 SHA-256 指纹 %q (应为 32 字节的 base64 编码)", s)
+	}
+	*f = append(*f, s)
+	return nil
+}
+
+// spkiSHA256 计算证书公钥信息的 SHA-256 指纹 (base64)
+func spkiSHA256(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// check 要求证书链中至少有一张证书的公钥与任一指纹匹配
+func (f pinFlag) check(state *tls.ConnectionState) error {
+	if state == nil {
+		return errors.New("未使用 TLS，无法校验证书指纹")
+	}
+	for _, cert := range state.PeerCertificates {
+		got := spkiSHA256(cert.RawSubjectPublicKeyInfo)
+		for _, pin := range f {
+			if got == pin {
+				return nil
+			}
+		}
+	}
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("服务端没有提供证书")
+	}
+	return fmt.Errorf("证书指纹不匹配: 服务端证书为 %s", spkiSHA256(state.PeerCertificates[0].RawSubjectPublicKeyInfo))
+}
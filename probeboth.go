@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// httpDialAddr 返回 HTTP 目标 URL 对应的 TCP 地址，未写端口时使用 -port 或协议默认端口
+func httpDialAddr(rawURL string, opts *Options) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	port := u.Port()
+	if port == "" {
+		port = opts.Port
+	}
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// pingBoth 实现 -probe-both: 先单独测试 TCP 端口是否开放，再发起 HTTP 请求，
+// 两者都成功才算成功，从而区分 "端口关闭" 和 "端口开放但应用异常"。
+func pingBoth(target string, opts *Options) PingResult {
+	addr, err := httpDialAddr(httpURL(target, opts), opts)
+	if err != nil {
+		return PingResult{Target: target, Error: err}
+	}
+
+	tcp := pingTCP(addr, opts)
+	if !tcp.Success {
+		tcp.Target = target
+		tcp.ConnectTime = tcp.ResponseTime
+		tcp.Error = fmt.Errorf("端口未开放: %w", tcp.Error)
+		return tcp
+	}
+
+	result := pingHTTP(target, opts)
+	result.ConnectTime = tcp.ResponseTime
+	if !result.Success {
+		if result.Error == nil {
+			result.Error = fmt.Errorf("状态码 %d", result.StatusCode)
+		}
+		result.Error = fmt.Errorf("端口已开放但 HTTP 检查失败: %w", result.Error)
+	}
+	return result
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// proxyEntry 是 -proxy-list 中的一个代理
+type proxyEntry struct {
+	label string   // 输出中显示的名称，默认为代理地址
+	url   *url.URL // http://host:port 或 socks5://host:port，可带 user:pass@
+}
+
+// proxyRotation 实现 -proxy-list 的轮换: 每个检查的每次探测依次使用下一个代理。
+// 每个检查为每个代理派生一份选项，-keepalive 的连接池和 WebSocket 连接按代理分开保持
+type proxyRotation struct {
+	entries []proxyEntry
+
+	mu     sync.Mutex
+	checks map[proxyCheckKey]*proxyCheck
+}
+
+// proxyCheckKey 标识一个检查: 同一份选项下的多个目标分别轮换
+type proxyCheckKey struct {
+	opts   *Options
+	target string
+}
+
+// proxyCheck 是一个检查的轮换状态
+type proxyCheck struct {
+	derived []*Options // 与 entries 一一对应
+	next    int
+}
+
+// loadProxyList 读取代理列表文件: 每行一个代理 URL，后面可以跟一个名称 (如所在地区)，
+// 空行和 # 开头的行被忽略
+func loadProxyList(path string) (*proxyRotation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &proxyRotation{checks: make(map[proxyCheckKey]*proxyCheck)}
+	for n, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("第 %d 行: 格式应为 URL [名称]", n+1)
+		}
+		u, err := url.Parse(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %v", n+1, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "socks5" {
+			return nil, fmt.Errorf("第 %d 行: 不支持的代理协议 %q (可选 http, socks5)", n+1, u.Scheme)
+		}
+		if u.Port() == "" {
+			return nil, fmt.Errorf("第 %d 行: 代理地址缺少端口", n+1)
+		}
+		entry := proxyEntry{label: u.Host, url: u}
+		if len(fields) == 2 {
+			entry.label = fields[1]
+		}
+		if slices.ContainsFunc(r.entries, func(e proxyEntry) bool { return e.label == entry.label }) {
+			return nil, fmt.Errorf("第 %d 行: 代理 %s 重复", n+1, entry.label)
+		}
+		r.entries = append(r.entries, entry)
+	}
+	if len(r.entries) == 0 {
+		return nil, fmt.Errorf("%s 中没有代理", path)
+	}
+	return r, nil
+}
+
+// labels 按列表顺序返回各代理的名称
+func (r *proxyRotation) labels() []string {
+	labels := make([]string, len(r.entries))
+	for i, e := range r.entries {
+		labels[i] = e.label
+	}
+	return labels
+}
+
+// pick 返回 target 本次探测使用的代理名称和经由该代理拨号的选项
+func (r *proxyRotation) pick(opts *Options, target string) (string, *Options) {
+	key := proxyCheckKey{opts, target}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.checks[key]
+	if c == nil {
+		c = &proxyCheck{derived: make([]*Options, len(r.entries))}
+		for k := range r.entries {
+			o := *opts
+			o.Proxies = nil
+			o.proxy = &r.entries[k]
+			c.derived[k] = &o
+		}
+		r.checks[key] = c
+	}
+	i := c.next
+	c.next = (c.next + 1) % len(r.entries)
+	return r.entries[i].label, c.derived[i]
+}
+
+// dialContext 经由代理连接 address: HTTP 代理使用 CONNECT 隧道，SOCKS5 代理由代理解析域名
+func (e *proxyEntry) dialContext(ctx context.Context, timeout time.Duration, address string) (net.Conn, error) {
+	forward := &net.Dialer{Timeout: timeout}
+	if e.url.Scheme == "socks5" {
+		var auth *proxy.Auth
+		if e.url.User != nil {
+			password, _ := e.url.User.Password()
+			auth = &proxy.Auth{User: e.url.User.Username(), Password: password}
+		}
+		d, err := proxy.SOCKS5("tcp", e.url.Host, auth, forward)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := d.(proxy.ContextDialer).DialContext(ctx, "tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("代理 %s: %w", e.label, err)
+		}
+		return conn, nil
+	}
+
+	conn, err := forward.DialContext(ctx, "tcp", e.url.Host)
+	if err != nil {
+		return nil, fmt.Errorf("代理 %s: %w", e.label, err)
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+	if e.url.User != nil {
+		password, _ := e.url.User.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(e.url.User.Username()+":"+password)))
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("代理 %s: %w", e.label, err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("代理 %s: %w", e.label, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("代理 %s 拒绝 CONNECT: %s", e.label, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	// 服务器先发言的协议 (如 SMTP 问候语) 可能和 CONNECT 的回复一起到达
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn 先读出 bufio.Reader 中已缓冲的数据
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+// printProxyGroups 在统计信息之后按检查输出经由每个代理的丢包和延迟，对比不同出口的表现
+func printProxyGroups(labels []string, stats []*targetStats) {
+	for i, s := range stats {
+		fmt.Printf("%s=== 各代理对比: %s ===%s\n", ColorCyan, labels[i], ColorReset)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "代理\t发送\t丢包\t平均\t最大\t")
+		for _, p := range s.proxies {
+			sub := s.byProxy[p]
+			if sub == nil {
+				fmt.Fprintf(w, "%s\t0\t-\t-\t-\t\n", p)
+				continue
+			}
+			sum := sub.summary()
+			fmt.Fprintf(w, "%s\t%d\t%.1f%%\t%s\t%s\t\n", p, sum.Sent, sum.LossPercent, formatGroupAvg(sum.Avg, sum.Success), formatGroupAvg(sum.Max, sum.Success))
+		}
+		w.Flush()
+		fmt.Println()
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// quicSessionCache 在多次探测之间保存 TLS 会话票据，之后的握手可以恢复会话并尝试 0-RTT
+var quicSessionCache = tls.NewLRUClientSessionCache(64)
+
+// quicTicketWait 是握手完成后等待服务端发来会话票据的最长时间，不计入握手耗时
+const quicTicketWait = 200 * time.Millisecond
+
+// ticketCache 在共享的会话缓存上记录本次连接是否已经收到新的会话票据
+type ticketCache struct {
+	tls.ClientSessionCache
+	stored chan struct{}
+}
+
+func (c *ticketCache) Put(key string, cs *tls.ClientSessionState) {
+	c.ClientSessionCache.Put(key, cs)
+	if cs != nil {
+		select {
+		case c.stored <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// quicALPN 是 quic 探测声明的应用层协议，与 HTTP/3 服务端一致
+const quicALPN = "h3"
+
+// pingQUIC 只完成 QUIC 握手 (不发送 HTTP/3 请求)，结果时间为握手耗时。
+// 目标可以是 host[:port] 或 https:// URL，默认端口 443。
+func pingQUIC(target string, opts *Options) PingResult {
+	result := PingResult{Target: target}
+
+	hostport := target
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			result.Error = err
+			return result
+		}
+		hostport = u.Host
+	}
+	hostport = withDefaultPort(hostport, opts.defaultPort())
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	addr := hostport
+	if ip, ok := opts.overrideAddr(host, port); ok {
+		addr = net.JoinHostPort(ip, port)
+	} else if opts.ipNetwork() != "ip" && net.ParseIP(host) == nil {
+		// quic-go 自行解析地址，-resolve-family 4/6 时先解析出该协议族的地址
+		ips, err := net.DefaultResolver.LookupIP(context.Background(), opts.ipNetwork(), host)
+		if err != nil {
+			result.Error = err
+			return result
+		}
+		addr = net.JoinHostPort(ips[0].String(), port)
+	}
+
+	tickets := &ticketCache{ClientSessionCache: quicSessionCache, stored: make(chan struct{}, 1)}
+	tlsConf := &tls.Config{
+		ServerName:         host,
+		NextProtos:         []string{quicALPN},
+		ClientSessionCache: tickets,
+		RootCAs:            opts.TLSVerify.roots,
+	}
+	if !opts.TLSVerify.full() {
+		tlsConf.InsecureSkipVerify = true
+		tlsConf.VerifyConnection = func(cs tls.ConnectionState) error {
+			return opts.TLSVerify.verify(cs, host)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	conn, err := quic.DialAddrEarly(ctx, addr, tlsConf, &quic.Config{HandshakeIdleTimeout: opts.Timeout})
+	if err != nil {
+		result.ResponseTime = time.Since(start)
+		result.Error = err
+		return result
+	}
+	defer conn.CloseWithError(0, "")
+
+	// DialAddrEarly 在 0-RTT 可用时提前返回，握手真正完成才算成功
+	select {
+	case <-conn.HandshakeComplete():
+	case <-ctx.Done():
+		result.ResponseTime = time.Since(start)
+		result.Error = ctx.Err()
+		return result
+	}
+	result.ResponseTime = time.Since(start)
+	result.RemoteAddr = conn.RemoteAddr().String()
+
+	state := conn.ConnectionState()
+	result.QUICVersion = state.Version.String()
+	result.ALPN = state.TLS.NegotiatedProtocol
+	result.Resumed = state.TLS.DidResume
+	result.Used0RTT = state.Used0RTT
+	if len(state.TLS.PeerCertificates) > 0 {
+		result.CertNotAfter = state.TLS.PeerCertificates[0].NotAfter
+		result.TLSReport = opts.TLSVerify.report(&state.TLS, host)
+	}
+	if len(opts.Pins) > 0 {
+		if err := opts.Pins.check(&state.TLS); err != nil {
+			result.Error = err
+			return result
+		}
+	}
+	result.Success = true
+
+	// 票据在握手完成后才由服务端发出，立即关闭连接会错过它，下一次探测就无法恢复会话
+	select {
+	case <-tickets.stored:
+	case <-time.After(quicTicketWait):
+	case <-ctx.Done():
+	}
+	return result
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// -read-body 的取值，决定 HTTP 结果的响应时间测量到哪一步
+const (
+	readBodyNone    = "none"    // 响应时间为首字节时间 (TTFB)，不读取响应体
+	readBodyHeaders = "headers" // 响应时间为收到完整响应头的时间 (默认)
+	readBodyFull    = "full"    // 响应时间包含下载响应体 (最多 -read-limit 字节)
+)
+
+// parseReadBody 校验 -read-body
+func parseReadBody(s string) (string, error) {
+	switch s {
+	case readBodyNone, readBodyHeaders, readBodyFull:
+		return s, nil
+	}
+	return "", fmt.Errorf("应为 none、headers 或 full")
+}
+
+// drainBody 在 -read-body full 时读取剩余的响应体 (已读取 read 字节)，总量不超过 -read-limit。
+// 读取的是线上传输的原始字节，不解码，返回本次读取的字节数
+func (o *Options) drainBody(resp *http.Response, read int64) (int64, error) {
+	var r io.Reader = resp.Body
+	if o.ReadLimit > 0 {
+		if read >= o.ReadLimit {
+			return 0, nil
+		}
+		r = io.LimitReader(resp.Body, o.ReadLimit-read)
+	}
+	return io.Copy(io.Discard, r)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// recordFormat 是 -record 文件格式的版本，-replay 只接受相同版本
+const recordFormat = 1
+
+// recordHeader 是录制文件的第一行，记录回放时需要的检查列表
+type recordHeader struct {
+	Type     string // "header"
+	Format   int
+	PingType string
+	Targets  []string
+	Names    []string
+	Start    time.Time
+}
+
+// recordEntry 是录制文件中的一条结果 (Type=result) 或一轮结束标记 (Type=round)。
+// Error 以字符串保存，并记下分类以便回放后的错误标签和统计与原始运行一致
+type recordEntry struct {
+	Type   string
+	Seq    int
+	Offset time.Duration // 相对录制开始的时间
+	PingResult
+	Error         string
+	ErrorCategory string
+}
+
+// replayedError 是回放出的错误，分类沿用录制时的结果
+type replayedError struct {
+	msg      string
+	category string
+}
+
+func (e *replayedError) Error() string { return e.msg }
+
+// recordSink 实现 -record: 把每条结果和轮次边界写入 NDJSON 文件，
+// 文件只供 -replay 读取，字段随 PingResult 变化，不保证与 -o json 一样稳定
+type recordSink struct {
+	f      *os.File
+	w      *bufio.Writer
+	enc    *json.Encoder
+	header recordHeader
+}
+
+func newRecordSink(f *os.File, pingType string, targets, names []string) *recordSink {
+	w := bufio.NewWriter(f)
+	return &recordSink{
+		f:      f,
+		w:      w,
+		enc:    json.NewEncoder(w),
+		header: recordHeader{Type: "header", Format: recordFormat, PingType: pingType, Targets: targets, Names: names},
+	}
+}
+
+func (s *recordSink) WriteHeader([]string, string) error {
+	s.header.Start = time.Now()
+	return s.enc.Encode(s.header)
+}
+
+func (s *recordSink) WriteResult(result PingResult, seq int) error {
+	entry := recordEntry{Type: "result", Seq: seq, Offset: time.Since(s.header.Start), PingResult: result}
+	if result.Error != nil {
+		entry.Error = result.Error.Error()
+		entry.ErrorCategory = classifyError(result.Error)
+	}
+	if err := s.enc.Encode(entry); err != nil {
+		return err
+	}
+	// 录制到标准输出 (-record -) 时逐条写出，管道下游不必等到一轮结束
+	if s.f == os.Stdout {
+		return s.w.Flush()
+	}
+	return nil
+}
+
+func (s *recordSink) EndRound(_ []PingResult, seq int) error {
+	if err := s.enc.Encode(recordEntry{Type: "round", Seq: seq, Offset: time.Since(s.header.Start)}); err != nil {
+		return err
+	}
+	return s.w.Flush() // 每轮落盘，运行中断时录制文件仍然可用
+}
+
+func (s *recordSink) WriteSummary([]string, []*targetStats) error { return s.w.Flush() }
+
+func (s *recordSink) Close() error {
+	return errors.Join(s.w.Flush(), closeOutputFile(s.f))
+}
+
+// recording 是读入的 -replay 文件
+type recording struct {
+	header  recordHeader
+	entries []recordEntry
+}
+
+func loadRecording(path string) (*recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	rec := &recording{}
+	if err := dec.Decode(&rec.header); err != nil {
+		return nil, fmt.Errorf("读取文件头失败: %w", err)
+	}
+	if rec.header.Type != "header" || rec.header.Format != recordFormat {
+		return nil, fmt.Errorf("不是 -record 生成的文件或格式版本不支持 (%d)", rec.header.Format)
+	}
+	if len(rec.header.Names) != len(rec.header.Targets) {
+		return nil, errors.New("文件头中的检查名称与目标数量不一致")
+	}
+	for line := 2; dec.More(); line++ {
+		var e recordEntry
+		if err := dec.Decode(&e); err != nil {
+			return nil, fmt.Errorf("第 %d 条记录: %w", line, err)
+		}
+		rec.entries = append(rec.entries, e)
+	}
+	return rec, nil
+}
+
+// play 通过正常的输出路径重新输出录制的结果，instant 为 false 时按原始时间间隔输出。
+// 返回回放的轮数；收到中断信号时提前结束。
+func (r *recording) play(sinks OutputSink, stats []*targetStats, statsMu *sync.Mutex, stop <-chan struct{}, instant bool) int {
+	index := make(map[string]int, len(stats)) // 检查名称 -> 下标
+	for i, t := range r.header.Targets {
+		index[checkLabel(t, r.header.Names[i])] = i
+	}
+
+	start := time.Now()
+	rounds := 0
+	var round []PingResult
+	for _, e := range r.entries {
+		if !instant && !sleepOrStop(time.Until(start.Add(e.Offset)), stop) {
+			break
+		}
+		if stopped(stop) {
+			break
+		}
+		if e.Type == "round" {
+			reportOutput(sinks.EndRound(round, e.Seq))
+			round = nil
+			rounds = e.Seq
+			continue
+		}
+
+		result := e.PingResult
+		if e.Error != "" {
+			result.Error = &replayedError{msg: e.Error, category: e.ErrorCategory}
+		}
+		i, ok := index[result.label()]
+		if !ok {
+			continue
+		}
+		statsMu.Lock()
+		stats[i].add(result)
+		statsMu.Unlock()
+		round = append(round, result)
+		reportOutput(sinks.WriteResult(result, e.Seq))
+	}
+	return rounds
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// pinResolved 实现 -resolve-once: 启动时把每个主机名目标解析一次，并把第一个地址
+// 作为 -resolve 覆盖写入 opts，整个运行期间都连接同一个地址。
+// 默认每次拨号都重新解析 (Go 不缓存 DNS 结果)，能反映 DNS 变化，但也会把解析耗时和
+// 轮询到的不同地址混进测量结果。IP 目标和已被 -resolve/-hostfile 覆盖的主机名保持不变。
+func pinResolved(targets []string, opts *Options) error {
+	if opts.Resolve == nil {
+		opts.Resolve = resolveFlag{}
+	}
+	for _, t := range targets {
+		host, port := targetHost(t, opts)
+		if _, ok := opts.overrideAddr(host, port); ok || net.ParseIP(host) != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		addrs, err := opts.lookupIPAddr(ctx, host)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("解析 %s 失败: %w", host, err)
+		}
+		ip := addrs[0].String()
+		opts.Resolve[strings.ToLower(host)] = ip
+		if len(addrs) > 1 {
+			fmt.Fprintf(os.Stderr, ColorYellow+"-resolve-once: %s 固定为 %s (共解析出 %d 个地址)\n"+ColorReset, host, ip, len(addrs))
+		} else {
+			fmt.Fprintf(os.Stderr, ColorYellow+"-resolve-once: %s 固定为 %s\n"+ColorReset, host, ip)
+		}
+	}
+	return nil
+}
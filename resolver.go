@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// setResolver 按 -resolver 选择 DNS 解析器，必须在第一次解析之前调用:
+// go 使用 Go 内置解析器，直接读取 /etc/resolv.conf 和 /etc/hosts 并发送 DNS 查询；
+// cgo 通过 C 库的 getaddrinfo 解析，遵循 nsswitch.conf、mDNS 等系统配置。
+// 为空时由 Go 运行时按平台和系统配置自动选择。
+func setResolver(name string) error {
+	switch name {
+	case "":
+		return nil
+	case "go":
+		net.DefaultResolver.PreferGo = true
+	case "cgo":
+		if !cgoResolver {
+			return fmt.Errorf("当前程序构建时未启用 cgo，无法使用 cgo 解析器")
+		}
+		net.DefaultResolver.PreferGo = false
+	default:
+		return fmt.Errorf("未知的解析器 %q (可选 go 或 cgo)", name)
+	}
+	// PreferGo=false 只表示不强制使用 Go 解析器，运行时仍可能自动选择它；
+	// netdns 在第一次解析时才读取，此时通过 GODEBUG 固定选择
+	godebug := os.Getenv("GODEBUG")
+	if godebug != "" {
+		godebug += ","
+	}
+	return os.Setenv("GODEBUG", godebug+"netdns="+name)
+}
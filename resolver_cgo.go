@@ -0,0 +1,6 @@
+//go:build cgo && !netgo
+
+package main
+
+// cgoResolver 表示程序可以使用 cgo 解析器
+const cgoResolver = true
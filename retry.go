@@ -0,0 +1,39 @@
+package main
+
+import "net/http"
+
+// retryBudget 限制整个运行期间的重试总次数，避免网络抖动时产生无上限的额外流量
+type retryBudget struct {
+	remaining int // 剩余预算，负数表示不限制
+}
+
+// take 尝试消耗一次重试预算，预算耗尽时返回 false
+func (b *retryBudget) take() bool {
+	if b.remaining == 0 {
+		return false
+	}
+	if b.remaining > 0 {
+		b.remaining--
+	}
+	return true
+}
+
+// idempotentMethods 是 RFC 7231 第 4.2.2 节定义的幂等方法，重复发送与发送一次的效果相同
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// retrySafe 判断失败的结果能否重试。非 HTTP 探测、-connect-only 和幂等方法总是可以；
+// POST、PATCH 等非幂等方法只在请求没有发出 (连接没有建立) 时重试，避免重复提交，
+// 指定 -retry-unsafe 时不做限制
+func (o *Options) retrySafe(result PingResult) bool {
+	if o.RetryUnsafe || !isHTTPType(o.PingType) || o.ConnectOnly || idempotentMethods[o.Method] {
+		return true
+	}
+	return result.BytesSent == 0
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// runReport 记录整个运行计划与实际执行的对比，用于判断结果是否因提前结束而不完整
+type runReport struct {
+	planned  time.Duration // -for 指定的时长，0 表示按 -c 次数运行
+	rounds   int           // -c 指定的轮数，-for 时为按固定间隔估算的轮数，0 表示无法估算
+	start    time.Time
+	finished int    // 完整执行的轮数
+	reason   string // 提前结束的原因，空表示按计划完成
+}
+
+// newRunReport 在进入主循环前创建，count 小于 0 表示持续运行
+func newRunReport(count int, runFor, interval time.Duration, adaptive bool) *runReport {
+	r := &runReport{planned: runFor, start: time.Now()}
+	switch {
+	case runFor > 0 && interval > 0 && !adaptive:
+		r.rounds = int((runFor + interval - 1) / interval)
+	case runFor == 0 && count > 0:
+		r.rounds = count
+	}
+	return r
+}
+
+// print 输出运行概况: -for 运行总是输出，-c 运行只在提前结束时输出，持续运行没有计划不输出
+func (r *runReport) print() {
+	if r.planned == 0 && (r.rounds == 0 || r.reason == "") {
+		return
+	}
+	elapsed := formatDuration(time.Since(r.start), time.Millisecond)
+	fmt.Printf("%s=== 运行概况 ===%s\n", ColorCyan, ColorReset)
+	if r.planned > 0 {
+		fmt.Printf("计划时长: %v，实际运行: %v\n", r.planned, elapsed)
+	} else {
+		fmt.Printf("运行时长: %v\n", elapsed)
+	}
+	switch {
+	case r.rounds > 0 && r.planned > 0:
+		fmt.Printf("完成轮数: %d/%d (按间隔估算)\n", r.finished, r.rounds)
+	case r.rounds > 0:
+		fmt.Printf("完成轮数: %d/%d\n", r.finished, r.rounds)
+	default:
+		fmt.Printf("完成轮数: %d\n", r.finished)
+	}
+	if r.reason != "" {
+		fmt.Printf("%s提前结束: %s%s\n\n", ColorYellow, r.reason, ColorReset)
+	} else {
+		fmt.Printf("%s按计划完成%s\n\n", ColorGreen, ColorReset)
+	}
+}
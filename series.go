@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// seriesSink 实现 -dump-series: 按顺序写出所有成功探测的响应时间 (纳秒)，
+// 每行一个数值，便于直接用 R/Python/gnuplot 读取。withTime 为 true 时每行为
+// "Unix 时间戳(纳秒),响应时间(纳秒)" 两列。
+type seriesSink struct {
+	f        *os.File
+	withTime bool
+}
+
+func newSeriesSink(f *os.File, format string) (*seriesSink, error) {
+	switch format {
+	case "rtt":
+		return &seriesSink{f: f}, nil
+	case "timestamp,rtt":
+		return &seriesSink{f: f, withTime: true}, nil
+	}
+	return nil, fmt.Errorf("不支持的格式 %q (可选 rtt, timestamp,rtt)", format)
+}
+
+func (s *seriesSink) WriteHeader([]string, string) error { return nil }
+
+func (s *seriesSink) WriteResult(result PingResult, seq int) error {
+	if !result.Success {
+		return nil
+	}
+	line := strconv.FormatInt(int64(result.ResponseTime), 10) + "\n"
+	if s.withTime {
+		line = strconv.FormatInt(result.Timestamp.UnixNano(), 10) + "," + line
+	}
+	_, err := s.f.WriteString(line)
+	return err
+}
+
+func (s *seriesSink) EndRound([]PingResult, int) error            { return nil }
+func (s *seriesSink) WriteSummary([]string, []*targetStats) error { return nil }
+
+func (s *seriesSink) Close() error {
+	return closeOutputFile(s.f)
+}
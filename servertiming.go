@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseServerTiming 从 Server-Timing 响应头估算服务端处理时间 (dur 参数，单位毫秒)，
+// 例如 `db;dur=53, app;dur=47.2, cache;desc="Cache Read";dur=23.2`。
+// 指标之间常有重叠或嵌套 (db 包含在 app 内)，不能简单相加: 有名为 total 的指标时使用它，
+// 否则取最大的单个 dur。没有任何 dur 参数时返回 ok=false。
+func parseServerTiming(header http.Header) (server time.Duration, ok bool) {
+	var largest, total time.Duration
+	hasTotal := false
+	for _, line := range header.Values("Server-Timing") {
+		for _, metric := range strings.Split(line, ",") {
+			params := strings.Split(metric, ";")
+			name := strings.TrimSpace(params[0])
+			for _, param := range params[1:] {
+				key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+				if !found || !strings.EqualFold(key, "dur") {
+					continue
+				}
+				ms, err := strconv.ParseFloat(strings.Trim(value, `"`), 64)
+				if err != nil || ms < 0 {
+					continue
+				}
+				d := time.Duration(ms * float64(time.Millisecond))
+				if strings.EqualFold(name, "total") {
+					total, hasTotal = d, true
+				}
+				largest = max(largest, d)
+				ok = true
+			}
+		}
+	}
+	if hasTotal {
+		return total, true
+	}
+	return largest, ok
+}
@@ -0,0 +1,8 @@
+//go:build unix && !(darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import "os"
+
+// infoSignals 在没有 SIGINFO 的平台上为空
+var infoSignals []os.Signal
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// pauseSignals 在不支持 SIGUSR1 的平台上为空，暂停功能不可用
+var pauseSignals []os.Signal
+
+// summarySignals 在不支持 SIGQUIT 的平台上为空，无法按需打印统计
+var summarySignals []os.Signal
@@ -0,0 +1,14 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// pauseSignals 用于切换暂停/恢复 (kill -USR1 <pid>)
+var pauseSignals = []os.Signal{syscall.SIGUSR1}
+
+// summarySignals 用于在运行中打印当前统计: SIGQUIT (Ctrl+\)，BSD 和 macOS 上还有 SIGINFO (Ctrl+T)
+var summarySignals = append([]os.Signal{syscall.SIGQUIT}, infoSignals...)
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// defaultLossWindow 是 max_loss 规则未指定 window 时统计的最近结果数
+const defaultLossWindow = 10
+
+// slaRule 是配置文件中的一条告警规则，每条规则可以组合多个阈值
+type slaRule struct {
+	Name       string        `yaml:"name"`
+	MaxLatency time.Duration `yaml:"max_latency"` // 单次成功响应的最大延迟
+	MaxLoss    float64       `yaml:"max_loss"`    // 最近 window 个结果的最大丢包率 (百分比)
+	Window     int           `yaml:"window"`
+	CertExpiry time.Duration `yaml:"cert_expiry"` // 证书剩余有效期低于该值时告警
+}
+
+func (r *slaRule) validate() error {
+	if r.Name == "" {
+		return errors.New("规则缺少 name")
+	}
+	if r.MaxLatency <= 0 && r.MaxLoss <= 0 && r.CertExpiry <= 0 {
+		return fmt.Errorf("规则 %s 没有设置任何阈值 (max_latency, max_loss, cert_expiry)", r.Name)
+	}
+	if r.MaxLoss < 0 || r.MaxLoss > 100 {
+		return fmt.Errorf("规则 %s 的 max_loss 必须在 0 到 100 之间", r.Name)
+	}
+	if r.Window < 0 {
+		return fmt.Errorf("规则 %s 的 window 不能为负数", r.Name)
+	}
+	if r.MaxLoss > 0 && r.Window == 0 {
+		r.Window = defaultLossWindow
+	}
+	return nil
+}
+
+// violations 根据目标的最新结果和历史统计评估规则，返回所有违规描述
+func (r *slaRule) violations(stats *targetStats) []string {
+	last, ok := stats.last()
+	if !ok {
+		return nil
+	}
+
+	var out []string
+	if r.MaxLatency > 0 && last.Success && last.ResponseTime > r.MaxLatency {
+		out = append(out, fmt.Sprintf("延迟 %v 超过 %v",
+			formatDuration(last.ResponseTime, time.Millisecond), r.MaxLatency))
+	}
+	if window := stats.samples(r.Window); r.MaxLoss > 0 && len(window) >= r.Window {
+		failed := 0
+		for _, res := range window {
+			if !res.Success {
+				failed++
+			}
+		}
+		if loss := float64(failed) / float64(r.Window) * 100; loss > r.MaxLoss {
+			out = append(out, fmt.Sprintf("最近 %d 次丢包率 %.1f%% 超过 %.1f%%", r.Window, loss, r.MaxLoss))
+		}
+	}
+	if r.CertExpiry > 0 && !last.CertNotAfter.IsZero() {
+		if left := time.Until(last.CertNotAfter); left < r.CertExpiry {
+			out = append(out, fmt.Sprintf("证书将在 %s 过期 (剩余 %v，阈值 %v)",
+				last.CertNotAfter.Format("2006-01-02"), left.Round(time.Hour), r.CertExpiry))
+		}
+	}
+	return out
+}
+
+// checkSLA 在每轮结束后评估本轮探测过的目标，违规以醒目的颜色输出到标准错误
+func checkSLA(rules map[string][]slaRule, targets []string, stats []*targetStats, order []int) {
+	for _, i := range order {
+		for _, rule := range rules[targets[i]] {
+			for _, v := range rule.violations(stats[i]) {
+				fmt.Fprintf(os.Stderr, "%sSLA 违规 [%s] %s: %s%s\n", ColorPurple, rule.Name, targets[i], v, ColorReset)
+			}
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// lookupSRVTargets 将 SRV 记录名展开为 host:port 目标列表。
+// net.LookupSRV 返回的记录已按优先级排序，同优先级内按权重随机排列，
+// 因此直接保持其顺序即可体现 priority/weight。
+func lookupSRVTargets(name string) ([]string, error) {
+	_, addrs, err := net.LookupSRV("", "", name)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		host := strings.TrimSuffix(addr.Target, ".")
+		targets = append(targets, net.JoinHostPort(host, strconv.Itoa(int(addr.Port))))
+	}
+	return targets, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshTunnelConfig 描述 -ssh 跳板机连接参数
+type sshTunnelConfig struct {
+	Spec     string // user@host[:port]
+	KeyFile  string // 私钥路径，为空时尝试 ~/.ssh 下的默认私钥
+	Insecure bool   // 跳过 known_hosts 主机密钥校验
+	Timeout  time.Duration
+}
+
+// parseSSHSpec 解析 user@host[:port]，用户名缺省为当前用户，端口缺省为 22
+func parseSSHSpec(spec string) (username, addr string, err error) {
+	username, host, ok := strings.Cut(spec, "@")
+	if !ok {
+		host = spec
+		u, err := user.Current()
+		if err != nil {
+			return "", "", fmt.Errorf("无法确定 SSH 用户名: %v", err)
+		}
+		username = u.Username
+	}
+	if host == "" || username == "" {
+		return "", "", fmt.Errorf("格式应为 user@host[:port]")
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), "22")
+	}
+	return username, host, nil
+}
+
+// sshAuthMethods 收集可用的认证方式: ssh-agent 优先，其次是私钥文件
+func sshAuthMethods(keyFile string) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+
+	keyFiles := []string{keyFile}
+	if keyFile == "" {
+		home, _ := os.UserHomeDir()
+		keyFiles = []string{
+			filepath.Join(home, ".ssh", "id_ed25519"),
+			filepath.Join(home, ".ssh", "id_ecdsa"),
+			filepath.Join(home, ".ssh", "id_rsa"),
+		}
+	}
+	var signers []ssh.Signer
+	for _, path := range keyFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if keyFile != "" {
+				return nil, err
+			}
+			continue
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			var passErr *ssh.PassphraseMissingError
+			if errors.As(err, &passErr) && keyFile == "" {
+				continue // 带密码的默认私钥交给 ssh-agent 处理
+			}
+			return nil, fmt.Errorf("解析私钥 %s 失败: %v", path, err)
+		}
+		signers = append(signers, signer)
+	}
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("没有可用的认证方式 (未找到 ssh-agent 或私钥)")
+	}
+	return methods, nil
+}
+
+// dialSSH 建立到跳板机的 SSH 连接，之后的 TCP/HTTP 探测都通过它转发
+func dialSSH(cfg sshTunnelConfig) (*ssh.Client, error) {
+	username, addr, err := parseSSHSpec(cfg.Spec)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := sshAuthMethods(cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if !cfg.Insecure {
+		home, _ := os.UserHomeDir()
+		hostKeyCallback, err = knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+		if err != nil {
+			return nil, fmt.Errorf("读取 known_hosts 失败 (可使用 -ssh-insecure 跳过校验): %v", err)
+		}
+	}
+
+	return ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         cfg.Timeout,
+	})
+}
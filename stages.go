@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// stageResult 是 -probe-order 中一个阶段的结果
+type stageResult struct {
+	Name     string
+	Duration time.Duration
+	Error    string // 空表示该阶段成功
+}
+
+// probeStages 是 -probe-order 可用的阶段。每个阶段是一次独立的探测，使用自己的连接，
+// 耗时只是该次探测本身的时间 (例如 tls 包含重新建立 TCP 连接)
+var probeStages = map[string]func(target string, opts *Options) PingResult{
+	"dns":  pingDNSStage,
+	"tcp":  pingTCPStage,
+	"tls":  pingTLSStage,
+	"http": pingHTTP,
+}
+
+// parseProbeOrder 解析 -probe-order，如 dns,tcp,tls,http
+func parseProbeOrder(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var order []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := probeStages[name]; !ok {
+			return nil, fmt.Errorf("未知的阶段 %q (可选 dns, tcp, tls, http)", name)
+		}
+		if slices.Contains(order, name) {
+			return nil, fmt.Errorf("阶段 %s 重复", name)
+		}
+		order = append(order, name)
+	}
+	return order, nil
+}
+
+// pingStages 实现 -probe-order: 按顺序执行各阶段，遇到第一个失败的阶段即停止并报告是哪个阶段失败。
+// 结果的其余字段来自最后执行的阶段，响应时间为各阶段耗时之和
+func pingStages(target string, opts *Options) PingResult {
+	var stages []stageResult
+	var total time.Duration
+	var result PingResult
+	for _, name := range opts.ProbeOrder {
+		result = probeStages[name](target, opts)
+		total += result.ResponseTime
+		stage := stageResult{Name: name, Duration: result.ResponseTime}
+		if !result.Success {
+			if result.Error == nil {
+				result.Error = fmt.Errorf("状态码 %d", result.StatusCode)
+			}
+			stage.Error = result.Error.Error()
+			result.Error = fmt.Errorf("阶段 %s 失败: %w", name, result.Error)
+			stages = append(stages, stage)
+			break
+		}
+		stages = append(stages, stage)
+	}
+	result.Target = target
+	result.ResponseTime = total
+	result.Stages = stages
+	return result
+}
+
+// pingDNSStage 解析目标的主机名，IP 目标和 -resolve/-hostfile 覆盖的主机名直接成功
+func pingDNSStage(target string, opts *Options) PingResult {
+	result := PingResult{Target: target}
+	host, port := targetHost(httpURL(target, opts), opts)
+	if _, ok := opts.overrideAddr(host, port); ok || net.ParseIP(host) != nil {
+		result.Success = true
+		return result
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	start := time.Now()
+	addrs, err := opts.lookupIPAddr(ctx, host)
+	result.ResponseTime = time.Since(start)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.RemoteAddr = addrs[0].String()
+	result.Success = true
+	return result
+}
+
+// pingTCPStage 测试 HTTP 目标的 TCP 端口
+func pingTCPStage(target string, opts *Options) PingResult {
+	addr, err := httpDialAddr(httpURL(target, opts), opts)
+	if err != nil {
+		return PingResult{Target: target, Error: err}
+	}
+	return pingTCP(addr, opts)
+}
+
+// pingTLSStage 建立连接并完成 TLS 握手，校验证书 (-verify、-pin-sha256)，不发送请求
+func pingTLSStage(target string, opts *Options) PingResult {
+	if !strings.HasPrefix(httpURL(target, opts), "https://") {
+		return PingResult{Target: target, Error: fmt.Errorf("tls 阶段只适用于 https 目标")}
+	}
+	// -check 表达式针对完整的 HTTP 响应，由 http 阶段求值
+	o := *opts
+	o.Script = nil
+	return pingConnectOnly(target, &o)
+}
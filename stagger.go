@@ -0,0 +1,18 @@
+package main
+
+import (
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// staggerOffsets 为 n 个目标的首次探测生成 [0, window) 内随机且升序的偏移，
+// 避免所有目标在启动时同时发出请求
+func staggerOffsets(n int, window time.Duration) []time.Duration {
+	offsets := make([]time.Duration, n)
+	for i := range offsets {
+		offsets[i] = rand.N(window)
+	}
+	slices.Sort(offsets)
+	return offsets
+}
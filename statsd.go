@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// statsdUnsafe 是 StatsD 指标名和 DogStatsD 标签中有特殊含义的字符 (分隔符 : | # , 和空白)
+var statsdUnsafe = regexp.MustCompile(`[:|#,@\s]`)
+
+// statsdNameUnsafe 是不带标签的 StatsD 指标名中不能出现的字符，点号和 Graphite 层级冲突也一并替换
+var statsdNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// statsdSink 实现 -statsd: 每次探测通过 UDP 发送 ping.rtt (timing，仅成功时) 和
+// ping.success / ping.failure (counter)。dogstatsd 格式以 |#target:... 标签区分检查，
+// 普通 StatsD 没有标签，把检查名称拼接到指标名末尾，如 ping.rtt.example_com。
+// UDP 发送是尽力而为的，失败不影响探测，只在结束时报告失败次数。
+type statsdSink struct {
+	conn    net.Conn
+	addr    string
+	dog     bool
+	tags    tagFlag
+	sent    int
+	dropped int
+}
+
+func newStatsdSink(addr string, tags tagFlag, dog bool) (*statsdSink, error) {
+	conn, err := net.Dial("udp", withDefaultPort(addr, "8125"))
+	if err != nil {
+		return nil, err
+	}
+	return &statsdSink{conn: conn, addr: addr, dog: dog, tags: tags}, nil
+}
+
+func init() {
+	registerSink("statsd", sinkFormat{
+		help:       "通过 UDP 发送 StatsD 指标，dest 为 host[:port]",
+		allResults: true,
+		create: func(dest string, tags tagFlag) (OutputSink, error) {
+			return newStatsdSink(dest, tags, false)
+		},
+	})
+	registerSink("dogstatsd", sinkFormat{
+		help:       "同 statsd，以 DogStatsD 标签区分检查",
+		allResults: true,
+		create: func(dest string, tags tagFlag) (OutputSink, error) {
+			return newStatsdSink(dest, tags, true)
+		},
+	})
+}
+
+// metric 格式化一行指标，value 和 kind 形如 12.3|ms、1|c
+func (s *statsdSink) metric(name, value, kind string, result PingResult) string {
+	if !s.dog {
+		return fmt.Sprintf("%s.%s:%s|%s", name, statsdNameUnsafe.ReplaceAllString(result.label(), "_"), value, kind)
+	}
+	tags := []string{"target:" + statsdUnsafe.ReplaceAllString(result.Target, "_")}
+	if result.Name != "" {
+		tags = append(tags, "name:"+statsdUnsafe.ReplaceAllString(result.Name, "_"))
+	}
+	for _, t := range s.tags {
+		tags = append(tags, t.key+":"+statsdUnsafe.ReplaceAllString(t.value, "_"))
+	}
+	return fmt.Sprintf("%s:%s|%s|#%s", name, value, kind, strings.Join(tags, ","))
+}
+
+func (s *statsdSink) WriteHeader([]string, string) error { return nil }
+
+func (s *statsdSink) WriteResult(result PingResult, seq int) error {
+	var lines []string
+	if result.Success {
+		ms := strconv.FormatFloat(durationMs(result.ResponseTime), 'f', 3, 64)
+		lines = append(lines, s.metric("ping.rtt", ms, "ms", result), s.metric("ping.success", "1", "c", result))
+	} else {
+		lines = append(lines, s.metric("ping.failure", "1", "c", result))
+	}
+	// 多条指标以换行分隔放进同一个数据报，StatsD 和 DogStatsD 都支持
+	if _, err := s.conn.Write([]byte(strings.Join(lines, "\n"))); err != nil {
+		s.dropped++
+		return nil
+	}
+	s.sent++
+	return nil
+}
+
+func (s *statsdSink) EndRound([]PingResult, int) error            { return nil }
+func (s *statsdSink) WriteSummary([]string, []*targetStats) error { return nil }
+
+// Close 在标准错误报告发送失败的次数 (通常是本机没有 StatsD 代理在监听)
+func (s *statsdSink) Close() error {
+	if s.dropped > 0 {
+		fmt.Fprintf(os.Stderr, ColorYellow+"StatsD %s: 发送 %d 次，失败 %d 次\n"+ColorReset, s.addr, s.sent, s.dropped)
+	}
+	return s.conn.Close()
+}
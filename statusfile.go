@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// statusFileSchemaVersion 是 -status-file 文件格式的版本号，独立于 -o json 的输出版本
+const statusFileSchemaVersion = 1
+
+// statusFile 是 -status-file 写出的运行结束快照
+type statusFile struct {
+	SchemaVersion int                     `json:"schema_version"`
+	GeneratedAt   time.Time               `json:"generated_at"`
+	Targets       map[string]targetStatus `json:"targets"`
+}
+
+// targetStatus 是单个目标的最终状态，status 取自最后一次探测结果
+type targetStatus struct {
+	Status      string  `json:"status"` // up 或 down
+	Sent        int     `json:"sent"`
+	Success     int     `json:"success"`
+	LossPercent float64 `json:"loss_percent"`
+	AvgMs       float64 `json:"avg_ms"`
+	MinMs       float64 `json:"min_ms"`
+	MaxMs       float64 `json:"max_ms"`
+	Health      string  `json:"health"`
+	LastError   string  `json:"last_error"`
+}
+
+// statusFileSink 只在运行结束时工作，把各目标的最终状态原子地写入文件
+type statusFileSink struct {
+	path string
+}
+
+func (s *statusFileSink) WriteHeader([]string, string) error { return nil }
+func (s *statusFileSink) WriteResult(PingResult, int) error  { return nil }
+func (s *statusFileSink) EndRound([]PingResult, int) error   { return nil }
+func (s *statusFileSink) Close() error                       { return nil }
+
+func (s *statusFileSink) WriteSummary(targets []string, stats []*targetStats) error {
+	doc := statusFile{
+		SchemaVersion: statusFileSchemaVersion,
+		GeneratedAt:   time.Now(),
+		Targets:       make(map[string]targetStatus, len(targets)),
+	}
+	for i, t := range targets {
+		sum := stats[i].summary()
+		st := targetStatus{
+			Status:      "down",
+			Sent:        sum.Sent,
+			Success:     sum.Success,
+			LossPercent: sum.LossPercent,
+			AvgMs:       durationMs(sum.Avg),
+			MinMs:       durationMs(sum.Min),
+			MaxMs:       durationMs(sum.Max),
+			Health:      healthOf(sum.SuccessRate()).key,
+		}
+		if last, ok := stats[i].last(); ok {
+			if last.Success {
+				st.Status = "up"
+			} else if last.Error != nil {
+				st.LastError = last.Error.Error()
+			}
+		}
+		doc.Targets[checkLabel(t, stats[i].name)] = st
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, append(data, '\n'))
+}
+
+// writeFileAtomic 先写入同目录下的临时文件再重命名，读取方不会看到写了一半的文件
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// stopOnSignal 在收到 Ctrl+C 或 SIGTERM 时关闭返回的 channel，
+// 主循环据此结束当前轮次并照常输出统计和状态文件。
+func stopOnSignal() <-chan struct{} {
+	stop := make(chan struct{})
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ch
+		close(stop)
+		signal.Stop(ch) // 再次 Ctrl+C 时按默认行为立即退出
+	}()
+	return stop
+}
+
+// sleepOrStop 等待 d，提前收到停止信号时返回 false
+func sleepOrStop(d time.Duration, stop <-chan struct{}) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// stopped 判断是否已收到停止信号
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// summaryOnSignal 在收到 summarySignals 时打印当前的统计信息并继续运行，
+// 与 BSD ping 的 Ctrl+T 行为一致。mu 保护 stats，主循环更新统计时也需要持有。
+func summaryOnSignal(mu *sync.Mutex, targets []string, stats []*targetStats) {
+	if len(summarySignals) == 0 {
+		return
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, summarySignals...)
+	go func() {
+		for range ch {
+			mu.Lock()
+			fmt.Printf("\n%s[%s] 当前统计%s\n", ColorYellow, time.Now().Format("15:04:05"), ColorReset)
+			for i, target := range targets {
+				if len(targets) == 1 {
+					target = ""
+				}
+				printSummary(target, stats[i])
+			}
+			mu.Unlock()
+		}
+	}()
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "errors"
+
+// newSyslogSink 在没有 log/syslog 的平台上不可用
+func newSyslogSink(string) (OutputSink, error) {
+	return nil, errors.New("当前平台不支持 syslog")
+}
@@ -0,0 +1,74 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"log/syslog"
+	"strings"
+)
+
+// syslogSink 把每条结果写入 syslog: 成功为 info，收到响应但判定失败为 warning，
+// 没有收到响应 (超时、连接被拒等) 为 err
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+// newSyslogSink 连接本机 syslog，addr 非空时发往远程 rsyslog，
+// 格式为 host:port 或 tcp://host:port (默认 UDP)
+func newSyslogSink(addr string) (OutputSink, error) {
+	network := ""
+	if addr != "" {
+		network = "udp"
+		if n, a, ok := strings.Cut(addr, "://"); ok {
+			network, addr = n, a
+		}
+		addr = withDefaultPort(addr, "514")
+	}
+	w, err := syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_DAEMON, "ping-tool")
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{w: w}, nil
+}
+
+func (s *syslogSink) WriteHeader(targets []string, pingType string) error {
+	return s.w.Info(fmt.Sprintf("开始 %s ping %s", strings.ToUpper(pingType), strings.Join(targets, ", ")))
+}
+
+func (s *syslogSink) WriteResult(result PingResult, seq int) error {
+	switch {
+	case result.Success && result.StatusCode != 0:
+		return s.w.Info(fmt.Sprintf("[%d] 响应来自 %s: 状态=%d 时间=%v", seq, result.label(), result.StatusCode, result.ResponseTime))
+	case result.Success:
+		return s.w.Info(fmt.Sprintf("[%d] 响应来自 %s: 时间=%v", seq, result.label(), result.ResponseTime))
+	case result.StatusCode != 0 || result.Corrupted:
+		return s.w.Warning(fmt.Sprintf("[%d] 请求失败 %s: %s%v", seq, result.label(), errorTag(result.Error), result.Error))
+	default:
+		return s.w.Err(fmt.Sprintf("[%d] 请求失败 %s: %s%v", seq, result.label(), errorTag(result.Error), result.Error))
+	}
+}
+
+func (s *syslogSink) EndRound([]PingResult, int) error { return nil }
+
+func (s *syslogSink) WriteSummary(targets []string, stats []*targetStats) error {
+	for i, t := range targets {
+		sum := stats[i].summary()
+		msg := fmt.Sprintf("统计 %s: 发送=%d 成功=%d 丢包率=%.1f%% 平均=%v 状态=%s",
+			checkLabel(t, stats[i].name), sum.Sent, sum.Success, sum.LossPercent, sum.Avg, healthOf(sum.SuccessRate()).label)
+		var err error
+		if sum.Failed > 0 {
+			err = s.w.Warning(msg)
+		} else {
+			err = s.w.Info(msg)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syslogSink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// printRoundTable 以对齐的表格打印一轮中所有目标的结果。
+// tabwriter 按字节计算颜色转义序列的宽度，因此同一列的每个单元格都必须
+// 带上等长的颜色码才能保持对齐；表头和单元格内容只使用 ASCII 字符。
+// 表格按终端宽度截断过长的目标和错误信息，终端过窄时改用紧凑输出。
+func printRoundTable(round []PingResult, seq int) {
+	fmt.Printf("%s--- 第 %d 轮 ---%s\n", ColorCyan, seq, ColorReset)
+
+	rows := make([]tableRow, len(round))
+	for i, r := range round {
+		rows[i] = newTableRow(r)
+	}
+
+	width := terminalWidth()
+	if width > 0 && width < narrowTerminalWidth {
+		printCompactRows(rows, width)
+		return
+	}
+
+	// 目标列最多占三分之一宽度，剩余宽度留给错误信息
+	targetWidth, rttWidth := len("TARGET"), len("RTT")
+	for _, row := range rows {
+		targetWidth = max(targetWidth, displayWidth(row.target))
+		rttWidth = max(rttWidth, len(row.rtt))
+	}
+	errWidth := 0
+	if width > 0 {
+		targetWidth = min(targetWidth, max(len("TARGET"), width/3))
+		used := targetWidth + 2 + len("STATUS") + 2 + rttWidth + 2 + len("CODE") + 2
+		errWidth = max(len("ERROR"), width-used)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "TARGET\t%s\t%s\t%s\tERROR\n",
+		colorize(ColorReset, "STATUS"), colorize(ColorReset, "RTT"), colorize(ColorReset, "CODE"))
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			elide(row.target, targetWidth),
+			colorize(row.color, row.status),
+			colorize(row.color, row.rtt),
+			colorize(row.codeColor, row.code),
+			elide(row.errText, errWidth))
+	}
+	w.Flush()
+}
+
+// tableRow 是一个结果在表格中的各列内容
+type tableRow struct {
+	target, status, rtt, code, errText string
+	color, codeColor                   string
+}
+
+func newTableRow(r PingResult) tableRow {
+	row := tableRow{target: r.label(), status: "OK", color: ColorGreen, rtt: "-", code: "-", codeColor: ColorReset}
+	if !r.Success {
+		row.status, row.color = "FAIL", ColorRed
+	}
+	if r.Success {
+		row.rtt = formatDuration(r.ResponseTime, time.Millisecond)
+	}
+	if r.StatusCode > 0 {
+		row.code, row.codeColor = fmt.Sprint(r.StatusCode), statusCodeColor(r.StatusCode)
+	}
+	if r.Error != nil {
+		row.errText = errorTag(r.Error) + r.Error.Error()
+	}
+	return row
+}
+
+// printCompactRows 在窄终端上每个结果输出一行 "状态 延迟 目标"，目标按剩余宽度截断
+func printCompactRows(rows []tableRow, width int) {
+	for _, row := range rows {
+		detail := row.rtt
+		if row.status != "OK" {
+			detail = row.code
+		}
+		prefix := fmt.Sprintf("%-4s %-6s ", row.status, detail)
+		fmt.Printf("%s%s%s%s\n", row.color, prefix, ColorReset, elide(row.target, width-len(prefix)))
+	}
+}
+
+// colorize 用颜色包裹文本。ColorReset 比其他颜色码短一个字节，
+// 所以默认颜色改用等长的 "\033[39m" (默认前景色)。
+func colorize(color, text string) string {
+	if color == ColorReset {
+		color = "\033[39m"
+	}
+	return color + text + ColorReset
+}
+
+// statusCodeColor 按 HTTP 状态码类别选择颜色
+func statusCodeColor(code int) string {
+	switch {
+	case code >= 500:
+		return ColorRed
+	case code >= 300:
+		return ColorYellow
+	default:
+		return ColorGreen
+	}
+}
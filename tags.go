@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// tagKeyPattern 与 Prometheus 标签名的规则一致，保证标签在所有输出格式中都可用
+var tagKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedTagKeys 是 CSV 列和 logfmt 字段已使用的名称，标签不能与之重名
+var reservedTagKeys = []string{
+	"timestamp", "seq", "target", "success", "response_time_ms", "status_code", "error", "retries",
+	"bytes_sent", "bytes_received", "name", "ts", "type", "ok", "rtt", "ttfb", "total", "code", "category",
+	"sent", "failed", "loss", "avg", "min", "max", "p99", "health", "le", "trace_id", "anomaly",
+}
+
+// tag 是一个 -tag 键值对
+type tag struct {
+	key, value string
+}
+
+// tagFlag 实现可重复的 -tag key=value 参数，按首次出现的顺序保存，重复的键以后者为准
+type tagFlag []tag
+
+func (f *tagFlag) String() string {
+	parts := make([]string, len(*f))
+	for i, t := range *f {
+		parts[i] = t.key + "=" + t.value
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f *tagFlag) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	key = strings.TrimSpace(key)
+	if !ok || !tagKeyPattern.MatchString(key) {
+		return fmt.Errorf("格式应为 key=value，key 只能包含字母、数字和下划线且不能以数字开头")
+	}
+	if slices.Contains(reservedTagKeys, key) {
+		return fmt.Errorf("标签名 %q 与输出中的内置字段重名", key)
+	}
+	for i := range *f {
+		if (*f)[i].key == key {
+			(*f)[i].value = value
+			return nil
+		}
+	}
+	*f = append(*f, tag{key, value})
+	return nil
+}
+
+// jsonTags 转换为 JSON 输出中的 tags 对象，没有标签时返回 nil 以省略该字段
+func (f tagFlag) jsonTags() map[string]string {
+	if len(f) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(f))
+	for _, t := range f {
+		m[t.key] = t.value
+	}
+	return m
+}
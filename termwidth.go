@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"strconv"
+
+	"golang.org/x/term"
+)
+
+// narrowTerminalWidth 以下改用紧凑输出，放不下完整的表格列
+const narrowTerminalWidth = 50
+
+// terminalWidth 返回标准输出的终端宽度；不是终端时使用 COLUMNS 环境变量，
+// 都没有时返回 0 表示不限制宽度 (例如重定向到文件)
+func terminalWidth() int {
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		return w
+	}
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w
+	}
+	return 0
+}
+
+// displayWidth 估算字符串在终端中占用的列数，中日韩等全角字符按两列计算
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+func runeWidth(r rune) int {
+	if r >= 0x1100 && (r <= 0x115f || (r >= 0x2e80 && r <= 0xa4cf) ||
+		(r >= 0xac00 && r <= 0xd7a3) || (r >= 0xf900 && r <= 0xfaff) ||
+		(r >= 0xfe30 && r <= 0xfe4f) || (r >= 0xff00 && r <= 0xff60) ||
+		(r >= 0xffe0 && r <= 0xffe6)) {
+		return 2
+	}
+	return 1
+}
+
+// elide 把字符串截断到最多 width 列，被截断时以 … 结尾
+func elide(s string, width int) string {
+	if width <= 0 || displayWidth(s) <= width {
+		return s
+	}
+	n := 0
+	for i, r := range s {
+		if n+runeWidth(r) > width-1 {
+			return s[:i] + "…"
+		}
+		n += runeWidth(r)
+	}
+	return s
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// traceMaxHops 与 traceroute 默认的最大跳数一致
+const traceMaxHops = 30
+
+// traceHop 是 -trace 中单个跳数的累计统计
+type traceHop struct {
+	addr  string // 最近一次回复来自的地址
+	sent  int
+	recv  int
+	last  time.Duration
+	best  time.Duration
+	worst time.Duration
+	total time.Duration
+}
+
+func (h *traceHop) add(rtt time.Duration) {
+	h.recv++
+	h.last = rtt
+	h.total += rtt
+	if h.best == 0 || rtt < h.best {
+		h.best = rtt
+	}
+	if rtt > h.worst {
+		h.worst = rtt
+	}
+}
+
+// tracer 实现 MTR 式的逐跳探测: 每轮对 TTL 1..N 各发一个 ICMP Echo，
+// 中间路由器回复 Time Exceeded，目标回复 Echo Reply，据此统计每跳的延迟和丢包。
+type tracer struct {
+	dst     *net.IPAddr
+	v6      bool
+	conn    *icmp.PacketConn
+	id      int
+	payload []byte
+	timeout time.Duration
+	hops    [traceMaxHops]traceHop
+	reached int // 目标所在的跳数，0 表示还没有收到目标的回复
+}
+
+func newTracer(target string, opts *Options) (*tracer, error) {
+	host := strings.TrimSuffix(strings.TrimPrefix(target, "["), "]")
+	if addr, ok := opts.overrideAddr(host, ""); ok {
+		host = addr
+	}
+	dst, err := net.ResolveIPAddr(opts.ipNetwork(), host)
+	if err != nil {
+		return nil, err
+	}
+	v6 := dst.IP.To4() == nil
+
+	// 非特权 ICMP 套接字收不到 Time Exceeded，只能使用原始套接字
+	network, addr := "ip4:icmp", "0.0.0.0"
+	if v6 {
+		network, addr = "ip6:ipv6-icmp", "::"
+	}
+	conn, err := icmp.ListenPacket(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("-trace 需要 root 权限: %w", err)
+	}
+	return &tracer{
+		dst:     dst,
+		v6:      v6,
+		conn:    conn,
+		id:      os.Getpid() & 0xffff,
+		payload: icmpPayload(opts.ICMPSize, opts.ICMPPattern),
+		timeout: opts.Timeout,
+	}, nil
+}
+
+func (t *tracer) setTTL(ttl int) error {
+	if t.v6 {
+		return t.conn.IPv6PacketConn().SetHopLimit(ttl)
+	}
+	return t.conn.IPv4PacketConn().SetTTL(ttl)
+}
+
+// round 完成一轮探测。已经到达目标后只探测到目标所在的跳数。
+func (t *tracer) round() error {
+	maxTTL := traceMaxHops
+	if t.reached > 0 {
+		maxTTL = t.reached
+	}
+
+	echoType := icmp.Type(ipv4.ICMPTypeEcho)
+	if t.v6 {
+		echoType = ipv6.ICMPTypeEchoRequest
+	}
+
+	sentAt := make(map[int]time.Time, maxTTL) // seq -> 发送时间
+	ttlOf := make(map[int]int, maxTTL)        // seq -> TTL
+	for ttl := 1; ttl <= maxTTL; ttl++ {
+		seq := int(icmpSeq.Add(1) & 0xffff)
+		msg := icmp.Message{Type: echoType, Body: &icmp.Echo{ID: t.id, Seq: seq, Data: t.payload}}
+		packet, err := msg.Marshal(nil)
+		if err != nil {
+			return err
+		}
+		if err := t.setTTL(ttl); err != nil {
+			return err
+		}
+		sentAt[seq] = time.Now()
+		ttlOf[seq] = ttl
+		if _, err := t.conn.WriteTo(packet, t.dst); err != nil {
+			return err
+		}
+	}
+
+	rtts := make(map[int]time.Duration, maxTTL) // TTL -> 延迟
+	addrs := make(map[int]string, maxTTL)
+	reached := 0
+	if err := t.conn.SetReadDeadline(time.Now().Add(t.timeout)); err != nil {
+		return err
+	}
+	buf := make([]byte, 65536)
+	for {
+		n, peer, err := t.conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			return err
+		}
+		seq, final, ok := t.match(buf[:n])
+		if !ok {
+			continue
+		}
+		ttl, ok := ttlOf[seq]
+		if !ok {
+			continue
+		}
+		if _, dup := rtts[ttl]; dup {
+			continue
+		}
+		rtts[ttl] = time.Since(sentAt[seq])
+		addrs[ttl] = peer.String()
+		if final && (reached == 0 || ttl < reached) {
+			reached = ttl
+		}
+		if reached > 0 && t.answered(rtts, reached) {
+			break
+		}
+	}
+
+	// 目标之后的 TTL 也会被目标本身回复，不计入统计
+	if reached > 0 {
+		t.reached = reached
+		maxTTL = reached
+	}
+	for ttl := 1; ttl <= maxTTL; ttl++ {
+		hop := &t.hops[ttl-1]
+		hop.sent++
+		if rtt, ok := rtts[ttl]; ok {
+			hop.add(rtt)
+			hop.addr = addrs[ttl]
+		}
+	}
+	return nil
+}
+
+// answered 判断 1..reached 的每一跳是否都已收到回复
+func (t *tracer) answered(rtts map[int]time.Duration, reached int) bool {
+	for ttl := 1; ttl <= reached; ttl++ {
+		if _, ok := rtts[ttl]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// match 从收到的 ICMP 报文中取出本进程发出的 Echo 序列号，
+// final 表示回复来自目标本身 (Echo Reply) 而不是中间路由器
+func (t *tracer) match(b []byte) (seq int, final, ok bool) {
+	proto, replyType := 1, icmp.Type(ipv4.ICMPTypeEchoReply)
+	if t.v6 {
+		proto, replyType = 58, ipv6.ICMPTypeEchoReply
+	}
+	msg, err := icmp.ParseMessage(proto, b)
+	if err != nil {
+		return 0, false, false
+	}
+
+	if msg.Type == replyType {
+		echo, isEcho := msg.Body.(*icmp.Echo)
+		if !isEcho || echo.ID != t.id {
+			return 0, false, false
+		}
+		return echo.Seq, true, true
+	}
+
+	var data []byte
+	switch body := msg.Body.(type) {
+	case *icmp.TimeExceeded:
+		data = body.Data
+	case *icmp.DstUnreach:
+		data = body.Data // 目标不可达时同样视为到达终点
+		final = true
+	default:
+		return 0, false, false
+	}
+
+	// Data 是原始 IP 头加上原始 ICMP 报文的前 8 字节
+	headerLen := 40
+	if !t.v6 {
+		if len(data) < 1 {
+			return 0, false, false
+		}
+		headerLen = int(data[0]&0x0f) * 4
+	}
+	if len(data) < headerLen+8 {
+		return 0, false, false
+	}
+	inner := data[headerLen:]
+	if int(binary.BigEndian.Uint16(inner[4:6])) != t.id {
+		return 0, false, false
+	}
+	return int(binary.BigEndian.Uint16(inner[6:8])), final, true
+}
+
+// print 以 MTR 报告的格式输出每一跳的统计，不显示末尾一直没有回复的跳
+func (t *tracer) print(target string, seq int) {
+	last := t.reached
+	if last == 0 {
+		for i := traceMaxHops - 1; i >= 0; i-- {
+			if t.hops[i].recv > 0 {
+				last = i + 1
+				break
+			}
+		}
+	}
+
+	fmt.Printf("%s--- 路由追踪 %s (%s)，第 %d 轮 ---%s\n", ColorCyan, target, t.dst, seq, ColorReset)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "HOP\tHOST\tLOSS%\tSNT\tLAST\tAVG\tBEST\tWRST\t")
+	for i := 0; i < last; i++ {
+		h := &t.hops[i]
+		host := h.addr
+		if host == "" {
+			host = "???"
+		}
+		loss := float64(h.sent-h.recv) / float64(h.sent) * 100
+		avg := time.Duration(0)
+		if h.recv > 0 {
+			avg = h.total / time.Duration(h.recv)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%.1f\t%d\t%s\t%s\t%s\t%s\t\n", i+1, host, loss, h.sent,
+			traceMs(h.last, h.recv), traceMs(avg, h.recv), traceMs(h.best, h.recv), traceMs(h.worst, h.recv))
+	}
+	w.Flush()
+	if t.reached == 0 {
+		fmt.Println(ColorYellow + "尚未到达目标" + ColorReset)
+	}
+}
+
+// traceMs 把延迟格式化为毫秒，没有任何回复时显示 -
+func traceMs(d time.Duration, recv int) string {
+	if recv == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", durationMs(d))
+}
+
+// runTrace 执行 -trace。count 小于 0 时持续运行并在每轮后刷新屏幕，
+// 否则运行 count 轮后输出一次报告。
+func runTrace(target string, opts *Options, count int, interval time.Duration) error {
+	t, err := newTracer(target, opts)
+	if err != nil {
+		return err
+	}
+	defer t.conn.Close()
+
+	stop := stopOnSignal()
+	if count > 0 {
+		fmt.Printf("正在追踪到 %s (%s) 的路由，共 %d 轮...\n", target, t.dst, count)
+	}
+	seq := 0
+	for count < 0 || seq < count {
+		if err := t.round(); err != nil {
+			return err
+		}
+		seq++
+		if count < 0 {
+			fmt.Print("\033[H\033[2J")
+			t.print(target, seq)
+		}
+		if stopped(stop) || (count > 0 && seq >= count) {
+			break
+		}
+		if !sleepOrStop(interval, stop) {
+			break
+		}
+	}
+	if count > 0 {
+		t.print(target, seq)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// traceparentHeader 是 W3C Trace Context 规定的请求头
+const traceparentHeader = "Traceparent"
+
+// headerNamePattern 是 RFC 9110 中 token 允许的字符，即合法的请求头名称
+var headerNamePattern = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// parseTraceHeader 解析 -trace-header: traceparent 生成 W3C traceparent，
+// 其他值作为请求头名称 (如 X-Request-ID)，每次探测写入一个新的 UUID
+func parseTraceHeader(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	if !headerNamePattern.MatchString(s) {
+		return "", fmt.Errorf("无效的请求头名称 %q", s)
+	}
+	return http.CanonicalHeaderKey(s), nil
+}
+
+// setTraceHeader 为本次请求生成关联 ID 并写入 -trace-header 指定的请求头，返回输出中记录的 ID:
+// traceparent 为其中的 trace-id，其他请求头为完整的值。未启用时返回空字符串。
+func (o *Options) setTraceHeader(req *http.Request) string {
+	if o.TraceHeader == "" {
+		return ""
+	}
+	if o.TraceHeader == traceparentHeader {
+		traceID, parentID := randomHex(16), randomHex(8)
+		// 版本 00，flags 01 表示已采样，使服务端链路追踪记录这次请求
+		req.Header.Set(traceparentHeader, "00-"+traceID+"-"+parentID+"-01")
+		return traceID
+	}
+	id := newUUID()
+	req.Header.Set(o.TraceHeader, id)
+	return id
+}
+
+// randomHex 返回 n 个随机字节的十六进制表示。crypto/rand 生成的 ID 全为 0 的概率可以忽略，
+// traceparent 规定的全 0 无效值不另行处理
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// newUUID 生成 RFC 9562 第 4 版 (随机) UUID
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b)
+	return strings.Join([]string{h[0:8], h[8:12], h[12:16], h[16:20], h[20:]}, "-")
+}
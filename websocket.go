@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// wsConnKey 标识一个检查对一个目标的 WebSocket 连接
+type wsConnKey struct {
+	opts   *Options
+	target string
+}
+
+// wsConn 是跨轮次保持的 WebSocket 连接
+type wsConn struct {
+	ws    *websocket.Conn
+	count *countingConn
+}
+
+// wsConns 保存空闲的 WebSocket 连接。探测时取出、成功后放回，
+// 同一连接不会被 -bench 的并发探测同时使用
+var (
+	wsConns   = map[wsConnKey]*wsConn{}
+	wsConnsMu sync.Mutex
+)
+
+// wsSeq 使每条消息内容不同，用于从服务器推送的其它消息中认出回显
+var wsSeq atomic.Int64
+
+// wsURL 返回目标的 WebSocket 地址，目标未写 ws:// 或 wss:// 时按 ping 类型补全
+func wsURL(target string, opts *Options) string {
+	if strings.HasPrefix(target, "ws://") || strings.HasPrefix(target, "wss://") {
+		return target
+	}
+	return strings.ToLower(opts.PingType) + "://" + urlHost(target)
+}
+
+// dialWebSocket 建立连接并完成 WebSocket 握手 (wss 先完成 TLS 握手并校验证书)
+func dialWebSocket(target string, opts *Options, result *PingResult) (*wsConn, error) {
+	u, err := url.Parse(wsURL(target, opts))
+	if err != nil {
+		return nil, err
+	}
+	secure := u.Scheme == "wss"
+	origin := "http://" + u.Host
+	if secure {
+		origin = "https://" + u.Host
+	}
+	config, err := websocket.NewConfig(u.String(), origin)
+	if err != nil {
+		return nil, err
+	}
+	port := u.Port()
+	switch {
+	case port != "":
+	case opts.Port != "":
+		port = opts.Port
+	case secure:
+		port = "443"
+	default:
+		port = "80"
+	}
+
+	start := time.Now()
+	raw, err := opts.dial("tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return nil, err
+	}
+	count := &countingConn{Conn: raw}
+	result.RemoteAddr = raw.RemoteAddr().String()
+	if err := raw.SetDeadline(start.Add(opts.Timeout)); err != nil {
+		raw.Close()
+		return nil, err
+	}
+
+	var conn net.Conn = count
+	if secure {
+		tc := tls.Client(count, opts.TLSVerify.clientConfig(u.Hostname()))
+		if err := tc.Handshake(); err != nil {
+			raw.Close()
+			return nil, fmt.Errorf("TLS 握手失败: %w", err)
+		}
+		state := tc.ConnectionState()
+		if len(opts.Pins) > 0 {
+			if err := opts.Pins.check(&state); err != nil {
+				raw.Close()
+				return nil, err
+			}
+		}
+		if len(state.PeerCertificates) > 0 {
+			result.CertNotAfter = state.PeerCertificates[0].NotAfter
+		}
+		result.TLSReport = opts.TLSVerify.report(&state, u.Hostname())
+		conn = tc
+	}
+
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		raw.Close()
+		return nil, fmt.Errorf("WebSocket 握手失败: %w", err)
+	}
+	result.ConnectTime = time.Since(start)
+	return &wsConn{ws: ws, count: count}, nil
+}
+
+// pingWebSocket 在保持的 WebSocket 连接上发送一条文本消息，测量收到相同回显的往返时间。
+// 第一次探测 (以及连接断开后) 先建立连接，握手耗时记为连接时间，不计入往返时间。
+// 连接被关闭或重置报告为连接断开；等待回显超时报告为回显超时，
+// 两种情况都会丢弃连接，下一轮重新建立。
+func pingWebSocket(target string, opts *Options) PingResult {
+	result := PingResult{Target: target}
+	key := wsConnKey{opts, target}
+
+	wsConnsMu.Lock()
+	c := wsConns[key]
+	delete(wsConns, key)
+	wsConnsMu.Unlock()
+
+	if c != nil {
+		result.ConnReused = true
+		result.RemoteAddr = c.count.RemoteAddr().String()
+	} else {
+		var err error
+		if c, err = dialWebSocket(target, opts, &result); err != nil {
+			result.Error = err
+			return result
+		}
+	}
+	sent, recv := c.count.sent.Load(), c.count.received.Load()
+
+	rtt, err := wsEcho(c.ws, opts.Timeout)
+	result.BytesSent, result.BytesRecv = c.count.sent.Load()-sent, c.count.received.Load()-recv
+	if err != nil {
+		c.ws.Close()
+		result.ResponseTime = rtt
+		result.Error = err
+		return result
+	}
+	result.ResponseTime = rtt
+	result.Success = true
+
+	wsConnsMu.Lock()
+	if wsConns[key] == nil {
+		wsConns[key] = c
+		c = nil
+	}
+	wsConnsMu.Unlock()
+	if c != nil {
+		c.ws.Close()
+	}
+	return result
+}
+
+// wsEcho 发送一条消息并等待相同内容的回显，内容不同的消息 (服务器推送或迟到的回显) 被跳过
+func wsEcho(ws *websocket.Conn, timeout time.Duration) (time.Duration, error) {
+	msg := fmt.Sprintf("ping-tool %d", wsSeq.Add(1))
+	start := time.Now()
+	if err := ws.SetDeadline(start.Add(timeout)); err != nil {
+		return 0, err
+	}
+	if err := websocket.Message.Send(ws, msg); err != nil {
+		return time.Since(start), wsError(err)
+	}
+	for {
+		var reply string
+		if err := websocket.Message.Receive(ws, &reply); err != nil {
+			return time.Since(start), wsError(err)
+		}
+		if reply == msg {
+			return time.Since(start), nil
+		}
+	}
+}
+
+// wsError 区分回显超时和连接断开
+func wsError(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("等待回显超时: %w", err)
+	}
+	if isConnReset(err) {
+		return fmt.Errorf("WebSocket 连接断开: %w", explainReset(err))
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// splitWeight 拆分 -weighted 模式下的 target:weight，最后一个冒号后的整数为权重，
+// 因此 host:port:weight 和 [ipv6]:port:weight 同样适用。
+func splitWeight(s string) (target string, weight int, err error) {
+	i := strings.LastIndex(s, ":")
+	if i <= 0 || strings.HasSuffix(s[:i], ":") {
+		return "", 0, fmt.Errorf("目标 %q 缺少权重，格式应为 target:weight", s)
+	}
+	weight, err = strconv.Atoi(s[i+1:])
+	if err != nil || weight <= 0 {
+		return "", 0, fmt.Errorf("目标 %q 的权重必须为正整数", s)
+	}
+	return s[:i], weight, nil
+}
+
+// weightedPicker 按权重随机选择目标下标
+type weightedPicker struct {
+	cumulative []int
+	total      int
+}
+
+func newWeightedPicker(weights []int) *weightedPicker {
+	p := &weightedPicker{cumulative: make([]int, len(weights))}
+	for i, w := range weights {
+		p.total += w
+		p.cumulative[i] = p.total
+	}
+	return p
+}
+
+func (p *weightedPicker) pick() int {
+	n := rand.IntN(p.total)
+	for i, c := range p.cumulative {
+		if n < c {
+			return i
+		}
+	}
+	return len(p.cumulative) - 1
+}